@@ -153,23 +153,156 @@ func (c *Client) Add(name string) string {
 	return c.makeRequest([]string{"add", c.loggedAs, name})
 }
 
+// Makes a request to the server attempting to add a new session whose game
+// is a copy of the game of the session `source`, or of its starting
+// configuration if `start` is passed.
+// Fails if the user is not logged in.
+func (c *Client) Fork(source, name string, from ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"fork", c.loggedAs, source, name}, from...)
+	return c.makeRequest(request)
+}
+
 // Makes a request to the server attempting to start a session with the given
-// configuration.
+// configuration and `key=value` options.
+// Fails if the user is not logged in.
+func (c *Client) Start(name, config string, options ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"start", c.loggedAs, name, config}, options...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to resume a stopped session until
+// any of the `until=<condition>` options is met.
+// Fails if the user is not logged in.
+func (c *Client) Resume(name string, options ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"resume", c.loggedAs, name}, options...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to change the speed of a session
+// to a number of generations per second, an interval between generations or
+// max.
+// Fails if the user is not logged in.
+func (c *Client) Speed(name, speed string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"speed", c.loggedAs, name, speed})
+}
+
+// Makes a request to the server attempting to advance a stopped session by
+// `n` steps, one unless given, and returns the resulting board.
+// Fails if the user is not logged in.
+func (c *Client) Step(name string, n ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"step", c.loggedAs, name}, n...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to restore the starting
+// configuration of a session, restarting it if `run` is passed.
+// Fails if the user is not logged in.
+func (c *Client) Reset(name string, run ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"reset", c.loggedAs, name}, run...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to bring the cell at row `x` and
+// column `y` of a stopped session to life or kill it, `state` being alive or
+// dead.
+// Fails if the user is not logged in.
+func (c *Client) Set(name, x, y, state string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"set", c.loggedAs, name, x, y, state})
+}
+
+// Makes a request to the server attempting to flip the state of the cell at
+// row `x` and column `y` of a stopped session.
+// Fails if the user is not logged in.
+func (c *Client) Toggle(name, x, y string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"toggle", c.loggedAs, name, x, y})
+}
+
+// Makes a request to the server attempting to place a predefined pattern on
+// the board of a session with its top left cell at row `x` and column `y`,
+// optionally rotated and then flipped.
+// Fails if the user is not logged in.
+func (c *Client) Place(name, pattern, x, y string, orientation ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"place", c.loggedAs, name, pattern, x, y}, orientation...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to apply the given transforms to
+// the board of a stopped session.
 // Fails if the user is not logged in.
-func (c *Client) Start(name, config string) string {
+func (c *Client) Transform(name string, transforms ...string) string {
 	if c.loggedAs == defaultUserName {
 		return "not logged in"
 	}
-	return c.makeRequest([]string{"start", c.loggedAs, name, config})
+	request := append([]string{"transform", c.loggedAs, name}, transforms...)
+	return c.makeRequest(request)
 }
 
-// Makes a request to the server attempting to resume a stopped session.
+// Makes a request to the server attempting to kill all the cells of a
+// stopped session.
 // Fails if the user is not logged in.
-func (c *Client) Resume(name string) string {
+func (c *Client) Clear(name string) string {
 	if c.loggedAs == defaultUserName {
 		return "not logged in"
 	}
-	return c.makeRequest([]string{"resume", c.loggedAs, name})
+	return c.makeRequest([]string{"clear", c.loggedAs, name})
+}
+
+// Makes a request to the server attempting to replace the cells of a stopped
+// session with random ones, each alive with probability `density`.
+// Fails if the user is not logged in.
+func (c *Client) FillRandom(name, density string, seed ...string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	request := append([]string{"fill-random", c.loggedAs, name, density}, seed...)
+	return c.makeRequest(request)
+}
+
+// Makes a request to the server attempting to move a stopped session back by
+// `n` generation steps and returns the resulting board.
+// Fails if the user is not logged in.
+func (c *Client) Rewind(name, n string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"rewind", c.loggedAs, name, n})
+}
+
+// Makes a request to the server attempting to move a stopped session to the
+// given generation and returns the resulting board.
+// Fails if the user is not logged in.
+func (c *Client) Seek(name, generation string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"seek", c.loggedAs, name, generation})
 }
 
 // Makes a request to the server attempting to list the session on the server.
@@ -195,7 +328,7 @@ func (c *Client) Kill(name string) string {
 	return c.makeRequest([]string{"kill", c.loggedAs, name})
 }
 
-func (c *Client) displayGame(s chan os.Signal, name string) {
+func (c *Client) displayGame(s chan os.Signal, name string, viewport []string) {
 	for {
 		select {
 		case <-s:
@@ -203,7 +336,8 @@ func (c *Client) displayGame(s chan os.Signal, name string) {
 			signal.Reset(os.Interrupt)
 			return
 		default:
-			board := c.makeRequest([]string{"watch", c.loggedAs, name})
+			request := append([]string{"watch", c.loggedAs, name}, viewport...)
+			board := c.makeRequest(request)
 			clearScreen()
 			fmt.Println(board)
 			time.Sleep(time.Second)
@@ -212,18 +346,30 @@ func (c *Client) displayGame(s chan os.Signal, name string) {
 }
 
 // Makes a request to the server attempting to retrieve the current state of
-// the game associated with a session.
+// the game associated with a session. The shown region of the board may be
+// fixed by passing its top left cell's row and column and its number of rows
+// and columns.
 // Fails if the user is not logged in.
-func (c *Client) Watch(name string) string {
+func (c *Client) Watch(name string, viewport ...string) string {
 	if c.loggedAs == defaultUserName {
 		return "not logged in"
 	}
 	s := make(chan os.Signal, 2)
 	signal.Notify(s, os.Interrupt)
-	go c.displayGame(s, name)
+	go c.displayGame(s, name, viewport)
 	return "game is now being displayed"
 }
 
+// Makes a request to the server attempting to retrieve the statistics of the
+// game associated with a session.
+// Fails if the user is not logged in.
+func (c *Client) Stats(name string) string {
+	if c.loggedAs == defaultUserName {
+		return "not logged in"
+	}
+	return c.makeRequest([]string{"stats", c.loggedAs, name})
+}
+
 // Stops the application.
 func (c *Client) Exit() {
 	c.Disconnect()
@@ -16,19 +16,51 @@ type Executable interface {
 	AssertExecutable()
 }
 
+// Returns the name of the method a command refers to. Commands consisting of
+// several words separated by dashes refer to the method named after the words
+// joined in title case, e.g. `fill-random` refers to `FillRandom`.
+func methodName(command string) string {
+	words := strings.Split(command, "-")
+	for idx, word := range words {
+		words[idx] = strings.Title(word)
+	}
+	return strings.Join(words, "")
+}
+
+var stringType = reflect.TypeOf("")
+
+// Reports whether the method of type `methodType` takes only strings and
+// returns a single string, the only methods ExecuteCommand runs.
+func isCommand(methodType reflect.Type) bool {
+	if methodType.NumOut() != 1 || methodType.Out(0) != stringType {
+		return false
+	}
+	for idx := 0; idx < methodType.NumIn(); idx++ {
+		in := methodType.In(idx)
+		if methodType.IsVariadic() && idx == methodType.NumIn()-1 {
+			in = in.Elem()
+		}
+		if in != stringType {
+			return false
+		}
+	}
+	return true
+}
+
 // Return a reflect object which, when executed, will run the method described
 // by `anyType` and `command` and nil. `command` should be a string containing
 // the method name and the arguments for that method.
 // An error is returned if:
 //   - `command` is not a method of the type of `anyType`
 //   - `command` does not contain enough arguments for the method it describes
+// Variadic methods accept any number of trailing arguments.
 // NOTE: a method must be export for it to be executable.
 func Execute(anyType Executable, command string) ([]reflect.Value, error) {
 	commandSplit := strings.Split(command, " ")
 	commandName := commandSplit[0]
 	commandArgs := commandSplit[1:]
 
-	method := reflect.ValueOf(anyType).MethodByName(strings.Title(commandName))
+	method := reflect.ValueOf(anyType).MethodByName(methodName(commandName))
 	if !method.IsValid() {
 		errorMessage := commandName + " is not a valid action"
 		return []reflect.Value{},
@@ -36,7 +68,15 @@ func Execute(anyType Executable, command string) ([]reflect.Value, error) {
 	}
 	expectedArgsCnt := method.Type().NumIn()
 	givenArgsCnt := len(commandArgs)
-	if givenArgsCnt != expectedArgsCnt {
+	if method.Type().IsVariadic() {
+		expectedArgsCnt--
+		if givenArgsCnt < expectedArgsCnt {
+			errorMessage := fmt.Sprintf(
+				"wrong number of arguments passed to %s, expected at least %d, got %d",
+				commandName, expectedArgsCnt, givenArgsCnt)
+			return []reflect.Value{}, errors.New(errorMessage)
+		}
+	} else if givenArgsCnt != expectedArgsCnt {
 		errorMessage := fmt.Sprintf(
 			"wrong number of arguments passed to %s, expected %d, got %d",
 			commandName, expectedArgsCnt, givenArgsCnt)
@@ -50,3 +90,22 @@ func Execute(anyType Executable, command string) ([]reflect.Value, error) {
 
 	return method.Call(methodArgs), nil
 }
+
+// Runs the method described by `anyType` and `command`, like Execute, and
+// returns the string it returns. Only methods taking only strings and
+// returning a single string are run, so that values meant to be executed by
+// remote requests expose no other methods.
+// An error is returned in the same cases as for Execute or if the method does
+// not take only strings and return a string.
+func ExecuteCommand(anyType Executable, command string) (string, error) {
+	commandName := strings.Split(command, " ")[0]
+	method := reflect.ValueOf(anyType).MethodByName(methodName(commandName))
+	if method.IsValid() && !isCommand(method.Type()) {
+		return "", errors.New(commandName + " is not a valid action")
+	}
+	result, err := Execute(anyType, command)
+	if err != nil {
+		return "", err
+	}
+	return result[0].Interface().(string), nil
+}
@@ -0,0 +1,56 @@
+package executor
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type commands struct {
+	exited bool
+}
+
+func (c *commands) AssertExecutable() {}
+
+func (c *commands) Echo(words ...string) string {
+	return strings.Join(words, " ")
+}
+
+func (c *commands) Exit() {
+	c.exited = true
+}
+
+func (c *commands) Count(n int) string {
+	return strconv.Itoa(n)
+}
+
+func TestExecute(t *testing.T) {
+	c := &commands{}
+	result, err := Execute(c, "exit")
+	if err != nil || len(result) != 0 || !c.exited {
+		t.Fatalf("expected exit to run and return nothing, got %v, %v", result, err)
+	}
+	if _, err := Execute(c, "exit now"); err == nil {
+		t.Fatal("expected an error for too many arguments")
+	}
+	if _, err := Execute(c, "nonsense"); err == nil {
+		t.Fatal("expected an error for an unknown method")
+	}
+}
+
+func TestExecuteCommand(t *testing.T) {
+	c := &commands{}
+	response, err := ExecuteCommand(c, "echo a b")
+	if err != nil || response != "a b" {
+		t.Fatalf("expected a b, got %q, %v", response, err)
+	}
+	for _, command := range []string{"exit", "count 1", "assert-executable", "nonsense"} {
+		if _, err := ExecuteCommand(c, command); err == nil ||
+			err.Error() != strings.Split(command, " ")[0]+" is not a valid action" {
+			t.Fatalf("expected %s not to be a valid action, got %v", command, err)
+		}
+	}
+	if c.exited {
+		t.Fatal("expected exit not to run")
+	}
+}
@@ -0,0 +1,363 @@
+package life
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A pattern is the result of reading a configuration: the live cells, the
+// dimensions of the board they are placed on and the rule the configuration
+// asks for (empty if none is specified).
+// Unless `hasBoard` is set, the dimensions are those of the pattern's bounding
+// box rather than of the whole board.
+type pattern struct {
+	rows, cols int
+	cells      []Cell
+	rule       string
+	hasBoard   bool
+}
+
+func invalidConfig(reason string) error {
+	return errors.New("invalid config: " + reason)
+}
+
+// Reads a configuration from `r` detecting its format by content.
+// The supported formats are:
+//   - the LaaS grid: a line containing the number of rows and columns of the
+//     board and optionally the rule, followed by the rows themselves using '*'
+//     for live and '-' for dead cells
+//   - RLE, either with the standard `x = .., y = .., rule = ..` header or with
+//     a LaaS dimensions line in its place
+//   - plaintext (.cells): '!' comment lines followed by rows of '.' and 'O'
+//   - Life 1.05: a `#Life 1.05` line followed by `#P` cell blocks
+//   - Life 1.06: a `#Life 1.06` line followed by `x y` coordinates
+//
+// Configurations containing lines longer than 65536 characters are not
+// supported.
+func parseConfig(r io.Reader) (*pattern, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, invalidConfig("empty configuration")
+	}
+
+	switch header := strings.TrimSpace(lines[0]); {
+	case strings.HasPrefix(header, "#Life 1.05"):
+		return parseLife105(lines[1:])
+	case strings.HasPrefix(header, "#Life 1.06"):
+		return parseLife106(lines[1:])
+	}
+
+	rows, cols, rule, ok := parseDimensions(lines[0])
+	if !ok {
+		if isPlaintext(lines) {
+			return parsePlaintext(lines)
+		}
+		return parseRLE(lines)
+	}
+	p := &pattern{rows: rows, cols: cols, rule: rule, hasBoard: true}
+	body := lines[1:]
+	if isGrid(body) {
+		return p, parseGrid(p, body)
+	}
+	return p, parseRLEBody(p, body)
+}
+
+// Parses a LaaS dimensions line - the number of rows and columns separated by
+// a space, optionally followed by a rule.
+func parseDimensions(line string) (int, int, string, bool) {
+	dimensions := strings.Fields(line)
+	if len(dimensions) != 2 && len(dimensions) != 3 {
+		return 0, 0, "", false
+	}
+	rows, err := strconv.Atoi(dimensions[0])
+	if err != nil || rows <= 0 {
+		return 0, 0, "", false
+	}
+	cols, err := strconv.Atoi(dimensions[1])
+	if err != nil || cols <= 0 {
+		return 0, 0, "", false
+	}
+	rule := ""
+	if len(dimensions) == 3 {
+		rule = dimensions[2]
+	}
+	return rows, cols, rule, true
+}
+
+func isGrid(lines []string) bool {
+	for _, line := range lines {
+		if strings.Trim(line, "*-") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func parseGrid(p *pattern, lines []string) error {
+	if len(lines) != p.rows {
+		return invalidConfig("expected " + strconv.Itoa(p.rows) + " rows")
+	}
+	for x, row := range lines {
+		if len(row) > p.cols {
+			return invalidConfig("row " + strconv.Itoa(x+1) + " is too long")
+		}
+		for y, char := range row {
+			if char == '*' {
+				p.cells = append(p.cells, Cell{x, y})
+			}
+		}
+	}
+	return nil
+}
+
+// Parses a standard RLE configuration: optional `#` comment lines, the header
+// and the run-length encoded body terminated by '!'.
+func parseRLE(lines []string) (*pattern, error) {
+	p := new(pattern)
+	for len(lines) > 0 && strings.HasPrefix(lines[0], "#") {
+		lines = lines[1:]
+	}
+	if len(lines) == 0 {
+		return nil, invalidConfig("missing RLE header")
+	}
+	if err := parseRLEHeader(p, lines[0]); err != nil {
+		return nil, err
+	}
+	return p, parseRLEBody(p, lines[1:])
+}
+
+// Parses an RLE header of the form `x = m, y = n, rule = abc`. The rule is
+// optional. Note that `x` is the width and `y` the height of the pattern.
+func parseRLEHeader(p *pattern, header string) error {
+	var err error
+	fields := strings.Split(header, ",")
+	for idx, field := range fields {
+		keyValue := strings.SplitN(field, "=", 2)
+		if len(keyValue) != 2 {
+			return invalidConfig("malformed RLE header")
+		}
+		key := strings.TrimSpace(keyValue[0])
+		value := strings.TrimSpace(keyValue[1])
+		switch key {
+		case "x":
+			p.cols, err = strconv.Atoi(value)
+		case "y":
+			p.rows, err = strconv.Atoi(value)
+		case "rule":
+			// the rule is the last field and may itself contain commas, as
+			// in the bounded grids of Golly, e.g. B3/S23:T20,10
+			value = strings.TrimSpace(strings.Join(append([]string{value}, fields[idx+1:]...), ","))
+			if _, grid, found := strings.Cut(value, ":"); found {
+				return invalidConfig("the bounded grid :" + grid +
+					" of the RLE rule is not supported, use the topology option instead")
+			}
+			p.rule = value
+		default:
+			return invalidConfig("unknown RLE header field " + key)
+		}
+		if err != nil {
+			return invalidConfig("malformed RLE header")
+		}
+		if key == "rule" {
+			break
+		}
+	}
+	if p.rows <= 0 || p.cols <= 0 {
+		return invalidConfig("RLE header must specify positive x and y")
+	}
+	return nil
+}
+
+// Decodes the run-length encoded cells in `lines`. Runs may span several
+// lines, everything after the terminating '!' is ignored.
+func parseRLEBody(p *pattern, lines []string) error {
+	x, y := 0, 0
+	count := 0
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, char := range line {
+			switch {
+			case char >= '0' && char <= '9':
+				count = count*10 + int(char-'0')
+				continue
+			case char == ' ' || char == '\t':
+				continue
+			}
+			run := count
+			if run == 0 {
+				run = 1
+			}
+			count = 0
+			switch char {
+			case 'b', '.':
+				y += run
+			case 'o':
+				for i := 0; i < run; i++ {
+					p.cells = append(p.cells, Cell{x, y})
+					y++
+				}
+			case '$':
+				x += run
+				y = 0
+			case '!':
+				return checkBounds(p)
+			default:
+				return invalidConfig("unexpected symbol " + string(char) + " in RLE")
+			}
+		}
+	}
+	return invalidConfig("RLE is missing the terminating '!'")
+}
+
+func isPlaintext(lines []string) bool {
+	if strings.HasPrefix(lines[0], "!") {
+		return true
+	}
+	for _, line := range lines {
+		if strings.Trim(line, ".O") != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses a plaintext configuration. The board is the bounding box of its live
+// cells, blank rows and columns around them are dropped.
+func parsePlaintext(lines []string) (*pattern, error) {
+	p := new(pattern)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "!") {
+			continue
+		}
+		for y, char := range line {
+			switch char {
+			case 'O', '*':
+				p.cells = append(p.cells, Cell{p.rows, y})
+			case '.':
+			default:
+				return nil, invalidConfig("unexpected symbol " + string(char) + " in plaintext")
+			}
+		}
+		if len(line) > p.cols {
+			p.cols = len(line)
+		}
+		p.rows++
+	}
+	if p.rows == 0 || p.cols == 0 {
+		return nil, invalidConfig("empty configuration")
+	}
+	normalize(p)
+	return p, nil
+}
+
+// Parses the coordinates of a Life 1.05/1.06 line, `x` being the column and
+// `y` the row, as is customary for these formats.
+func parseCoordinates(line string) (Cell, error) {
+	coordinates := strings.Fields(line)
+	if len(coordinates) != 2 {
+		return Cell{}, invalidConfig("malformed coordinates " + line)
+	}
+	y, err := strconv.Atoi(coordinates[0])
+	if err != nil {
+		return Cell{}, invalidConfig("malformed coordinates " + line)
+	}
+	x, err := strconv.Atoi(coordinates[1])
+	if err != nil {
+		return Cell{}, invalidConfig("malformed coordinates " + line)
+	}
+	return Cell{x, y}, nil
+}
+
+// Parses the blocks of a Life 1.05 configuration. Each block starts with a
+// `#P x y` line giving the position of its top left corner, followed by rows of
+// '.' and '*'. The rule is given by a `#N` (normal) or `#R` line.
+func parseLife105(lines []string) (*pattern, error) {
+	p := new(pattern)
+	var corner Cell
+	row := 0
+	for _, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "#P"):
+			var err error
+			corner, err = parseCoordinates(line[2:])
+			if err != nil {
+				return nil, err
+			}
+			row = 0
+			continue
+		case strings.HasPrefix(line, "#N"):
+			p.rule = "23/3"
+			continue
+		case strings.HasPrefix(line, "#R"):
+			p.rule = strings.TrimSpace(line[2:])
+			continue
+		case strings.HasPrefix(line, "#"):
+			continue
+		}
+		for y, char := range line {
+			switch char {
+			case '*':
+				p.cells = append(p.cells, Cell{corner.X + row, corner.Y + y})
+			case '.':
+			default:
+				return nil, invalidConfig("unexpected symbol " + string(char) + " in Life 1.05")
+			}
+		}
+		row++
+	}
+	normalize(p)
+	return p, nil
+}
+
+// Parses a Life 1.06 configuration - a list of live cell coordinates.
+func parseLife106(lines []string) (*pattern, error) {
+	p := new(pattern)
+	for _, line := range lines {
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+		c, err := parseCoordinates(line)
+		if err != nil {
+			return nil, err
+		}
+		p.cells = append(p.cells, c)
+	}
+	normalize(p)
+	return p, nil
+}
+
+// Translates the cells of `p` so that its bounding box starts at the origin
+// and sets the dimensions of `p` to those of the bounding box.
+func normalize(p *pattern) {
+	if len(p.cells) == 0 {
+		p.rows, p.cols = 1, 1
+		return
+	}
+	minX, minY, maxX, maxY := boundingBox(p.cells)
+	for idx := range p.cells {
+		p.cells[idx].X -= minX
+		p.cells[idx].Y -= minY
+	}
+	p.rows, p.cols = maxX-minX+1, maxY-minY+1
+}
+
+func checkBounds(p *pattern) error {
+	for _, c := range p.cells {
+		if c.X >= p.rows || c.Y >= p.cols {
+			return invalidConfig("pattern does not fit in the specified dimensions")
+		}
+	}
+	return nil
+}
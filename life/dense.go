@@ -0,0 +1,169 @@
+package life
+
+import "hash/fnv"
+
+// A dense engine is a fixed size board storing a symbol for every cell. The
+// edges of the board are connected according to its topology.
+// The board holding the next generation is kept around so it does not have to
+// be allocated on every step.
+type denseEngine struct {
+	currentState [][]boardSymbol
+	tempState    [][]boardSymbol
+	dimX, dimY   int
+	topology     Topology
+	rule         Rule
+	births       int
+	deaths       int
+}
+
+func newBoard(x, y int) [][]boardSymbol {
+	board := make([][]boardSymbol, x)
+	for i := range board {
+		board[i] = make([]boardSymbol, y)
+		for j := range board[i] {
+			board[i][j] = dead
+		}
+	}
+	return board
+}
+
+func deep2DCopy(x, y int, board [][]boardSymbol) [][]boardSymbol {
+	res := make([][]boardSymbol, x)
+	for i := range board {
+		res[i] = make([]boardSymbol, y)
+		copy(res[i], board[i])
+	}
+	return res
+}
+
+func newDenseEngine(config EngineConfig) Engine {
+	return &denseEngine{
+		currentState: newBoard(config.Rows, config.Cols),
+		tempState:    newBoard(config.Rows, config.Cols),
+		dimX:         config.Rows,
+		dimY:         config.Cols,
+		topology:     config.Topology,
+		rule:         config.Rule,
+	}
+}
+
+func (e *denseEngine) Get(x, y int) bool {
+	x, y, onBoard := e.topology.wrap(x, y, e.dimX, e.dimY)
+	return onBoard && e.currentState[x][y] == alive
+}
+
+func (e *denseEngine) Set(x, y int, isAlive bool) bool {
+	x, y, onBoard := e.topology.wrap(x, y, e.dimX, e.dimY)
+	if !onBoard {
+		return false
+	}
+	if isAlive {
+		e.currentState[x][y] = alive
+	} else {
+		e.currentState[x][y] = dead
+	}
+	return true
+}
+
+func (e *denseEngine) getAliveNeighboursCnt(x, y int) uint8 {
+	var count uint8 = 0
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			if e.Get(x+dx, y+dy) {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func (e *denseEngine) Step() {
+	e.births, e.deaths = 0, 0
+	for x, row := range e.currentState {
+		for y, symbol := range row {
+			livesOn := e.rule.livesOn(symbol == alive, e.getAliveNeighboursCnt(x, y))
+			if livesOn {
+				e.tempState[x][y] = alive
+			} else {
+				e.tempState[x][y] = dead
+			}
+			if livesOn && symbol == dead {
+				e.births++
+			} else if !livesOn && symbol == alive {
+				e.deaths++
+			}
+		}
+	}
+	tmp := e.currentState
+	e.currentState = e.tempState
+	e.tempState = tmp
+}
+
+func (e *denseEngine) StepN(n int) {
+	for i := 0; i < n; i++ {
+		e.Step()
+	}
+}
+
+func (e *denseEngine) changes() (int, int) {
+	return e.births, e.deaths
+}
+
+func (e *denseEngine) Bounds() (int, int) {
+	return e.dimX, e.dimY
+}
+
+func (e *denseEngine) Population() int {
+	population := 0
+	for _, row := range e.currentState {
+		for _, symbol := range row {
+			if symbol == alive {
+				population++
+			}
+		}
+	}
+	return population
+}
+
+func (e *denseEngine) Snapshot() Snapshot {
+	var cells []Cell
+	for x, row := range e.currentState {
+		for y, symbol := range row {
+			if symbol == alive {
+				cells = append(cells, Cell{x, y})
+			}
+		}
+	}
+	return Snapshot(cells)
+}
+
+func (e *denseEngine) Restore(snapshot Snapshot) {
+	e.currentState = newBoard(e.dimX, e.dimY)
+	for _, c := range snapshot {
+		e.Set(c.X, c.Y, true)
+	}
+}
+
+func (e *denseEngine) Clone() Engine {
+	return &denseEngine{
+		currentState: deep2DCopy(e.dimX, e.dimY, e.currentState),
+		tempState:    newBoard(e.dimX, e.dimY),
+		dimX:         e.dimX,
+		dimY:         e.dimY,
+		topology:     e.topology,
+		rule:         e.rule,
+	}
+}
+
+func (e *denseEngine) stateHash() uint64 {
+	hash := fnv.New64a()
+	for _, row := range e.currentState {
+		for _, symbol := range row {
+			hash.Write([]byte{byte(symbol)})
+		}
+	}
+	return hash.Sum64()
+}
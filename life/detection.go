@@ -0,0 +1,184 @@
+package life
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"slices"
+)
+
+// The number of most recent states remembered when looking for repetitions.
+// Periods longer than this are not detected.
+const DetectionWindow = 1024
+
+// A Behaviour describes what a game has settled into, if anything.
+type Behaviour uint8
+
+const (
+	// No repetition has been observed yet.
+	Evolving Behaviour = iota
+	// There are no live cells left.
+	Extinct
+	// The state no longer changes.
+	StillLife
+	// The state repeats itself.
+	Oscillator
+	// The state repeats itself displaced (unbounded universes only).
+	Spaceship
+)
+
+// The Status of a game describes its behaviour, the number of generations
+// between repetitions of its state, the generation at which it began
+// repeating and, for spaceships, how far the pattern moves every period.
+type Status struct {
+	Behaviour    Behaviour
+	Period       uint64
+	Since        uint64
+	Displacement Cell
+}
+
+// Reports whether the game has died out or become periodic.
+func (s Status) Settled() bool {
+	return s.Behaviour != Evolving
+}
+
+// Returns the status in human readable form.
+func (s Status) String() string {
+	switch s.Behaviour {
+	case Extinct:
+		return fmt.Sprintf("extinct since generation %d", s.Since)
+	case StillLife:
+		return fmt.Sprintf("still life since generation %d", s.Since)
+	case Oscillator:
+		return fmt.Sprintf("oscillating with period %d since generation %d", s.Period, s.Since)
+	case Spaceship:
+		return fmt.Sprintf("moving by (%d, %d) every %d generations since generation %d",
+			s.Displacement.X, s.Displacement.Y, s.Period, s.Since)
+	}
+	return "evolving"
+}
+
+// Engines able to hash their state faster than by taking a snapshot.
+type stateHasher interface {
+	stateHash() uint64
+}
+
+type sighting struct {
+	generation uint64
+	offset     Cell
+}
+
+// A detector remembers the hashes of the most recent states of a game and
+// reports once a state repeats. Hash collisions are not checked for.
+type detector struct {
+	seen   map[uint64]sighting
+	recent []uint64
+	next   int
+	status Status
+}
+
+func newDetector() *detector {
+	return &detector{
+		seen:   make(map[uint64]sighting),
+		recent: make([]uint64, 0, DetectionWindow),
+	}
+}
+
+func (d *detector) clone() *detector {
+	clone := *d
+	clone.seen = maps.Clone(d.seen)
+	clone.recent = slices.Clone(d.recent)
+	return &clone
+}
+
+func hashCells(cells Snapshot, offset Cell) uint64 {
+	hash := fnv.New64a()
+	var buffer [16]byte
+	for _, c := range cells {
+		binary.LittleEndian.PutUint64(buffer[:8], uint64(c.X-offset.X))
+		binary.LittleEndian.PutUint64(buffer[8:], uint64(c.Y-offset.Y))
+		hash.Write(buffer[:])
+	}
+	return hash.Sum64()
+}
+
+// Hashes the state of `engine`. States of unbounded universes are hashed
+// relative to their bounding box so that spaceships are recognized; the
+// returned offset is the top left corner of the bounding box.
+func hashState(engine Engine, unbounded bool) (uint64, Cell) {
+	if hasher, ok := engine.(stateHasher); ok && !unbounded {
+		return hasher.stateHash(), Cell{}
+	}
+	cells := engine.Snapshot()
+	var offset Cell
+	if unbounded && len(cells) > 0 {
+		offset.X, offset.Y, _, _ = boundingBox(cells)
+	}
+	return hashCells(cells, offset), offset
+}
+
+// Records the state of `engine` at `generation` and updates the status. The
+// engine advances by `step` generations at a time, so a state recurring after
+// a single step may have any period dividing `step`; it is found by advancing
+// the engine returned by `single`, which computes a single generation per
+// step.
+// Once settled, the status does not change until the detector is reset.
+func (d *detector) observe(engine Engine, unbounded bool, generation, step uint64,
+	single func() Engine) {
+	if d.status.Settled() {
+		return
+	}
+	if engine.Population() == 0 {
+		d.status = Status{Behaviour: Extinct, Period: 1, Since: generation}
+		return
+	}
+
+	hash, offset := hashState(engine, unbounded)
+	if previous, found := d.seen[hash]; found {
+		d.status = Status{
+			Behaviour: Oscillator,
+			Period:    generation - previous.generation,
+			Since:     previous.generation,
+			Displacement: Cell{
+				offset.X - previous.offset.X,
+				offset.Y - previous.offset.Y,
+			},
+		}
+		if d.status.Displacement != (Cell{}) {
+			d.status.Behaviour = Spaceship
+		} else if d.status.Period == step {
+			if step > 1 {
+				d.status.Period = smallestPeriod(single(), step)
+			}
+			if d.status.Period == 1 {
+				d.status.Behaviour = StillLife
+			}
+		}
+		return
+	}
+
+	if len(d.recent) < DetectionWindow {
+		d.recent = append(d.recent, hash)
+	} else {
+		oldest := d.recent[d.next]
+		delete(d.seen, oldest)
+		d.recent[d.next] = hash
+		d.next = (d.next + 1) % DetectionWindow
+	}
+	d.seen[hash] = sighting{generation, offset}
+}
+
+// Returns the number of generations after which the state of `engine`, which
+// recurs after `step` generations, first recurs. Periods longer than the
+// DetectionWindow are not looked for, `step` is returned instead.
+func smallestPeriod(engine Engine, step uint64) uint64 {
+	start := engine.Snapshot()
+	for period := uint64(1); period < step && period <= DetectionWindow; period++ {
+		engine.Step()
+		if slices.Equal(engine.Snapshot(), start) {
+			return period
+		}
+	}
+	return step
+}
@@ -0,0 +1,63 @@
+package life
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// Brings the cell at `x`, `y` to life or kills it.
+// Fails if the cell is outside the board.
+func (l *Life) SetCell(x, y int, isAlive bool) error {
+	if !l.currentState.Set(x, y, isAlive) {
+		return fmt.Errorf("cell (%d, %d) is outside the board", x, y)
+	}
+	l.edited()
+	return nil
+}
+
+// Brings the cell at `x`, `y` to life if it is dead and kills it otherwise.
+// Fails if the cell is outside the board.
+func (l *Life) ToggleCell(x, y int) error {
+	return l.SetCell(x, y, !l.currentState.Get(x, y))
+}
+
+// Kills all the cells.
+func (l *Life) Clear() {
+	l.currentState.Restore(nil)
+	l.edited()
+}
+
+// Replaces the cells of the board with random ones, each alive with
+// probability `density`, drawn from a source seeded with `seed`. Unbounded
+// universes are filled in the region of their starting configuration.
+// Fails if the density is not between 0 and 1.
+func (l *Life) FillRandom(density float64, seed int64) error {
+	if !(density >= 0 && density <= 1) {
+		return errors.New("the density must be between 0 and 1")
+	}
+	random := rand.New(rand.NewSource(seed))
+	var cells []Cell
+	for x := 0; x < l.dimX; x++ {
+		for y := 0; y < l.dimY; y++ {
+			if random.Float64() < density {
+				cells = append(cells, Cell{x, y})
+			}
+		}
+	}
+	l.currentState.Restore(Snapshot(cells))
+	l.edited()
+	return nil
+}
+
+// Accounts for the cells of the current generation having been edited: the
+// edited state replaces it in the history and the status is detected anew.
+func (l *Life) edited() {
+	population := l.currentState.Population()
+	l.stats.amend(population)
+	l.detector = newDetector()
+	l.observe()
+	if l.history != nil {
+		l.history.amend(l.currentState.Snapshot())
+	}
+}
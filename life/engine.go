@@ -0,0 +1,178 @@
+package life
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The engines a game can be run on.
+const (
+	// A fixed size board, the default for all topologies but Unbounded.
+	DenseEngine = "dense"
+	// A set of the live cells, the default for the Unbounded topology.
+	SparseEngine = "sparse"
+	// A memoized quadtree able to advance many generations at once, only
+	// supports the Unbounded topology.
+	HashLifeEngine = "hashlife"
+	// A fixed size board storing 64 cells per word and stepped in parallel,
+	// only supports the Bounded and Torus topologies.
+	PackedEngine = "packed"
+)
+
+// A Cell is identified by its row (X) and column (Y).
+type Cell struct {
+	X, Y int
+}
+
+// A Snapshot is the set of live cells of a game, sorted by row and then by
+// column so that equal states have equal snapshots.
+type Snapshot []Cell
+
+func newSnapshot(cells []Cell) Snapshot {
+	slices.SortFunc(cells, func(a, b Cell) int {
+		if a.X != b.X {
+			return cmp.Compare(a.X, b.X)
+		}
+		return cmp.Compare(a.Y, b.Y)
+	})
+	return Snapshot(cells)
+}
+
+// An Engine stores the cells of a game and computes its generations.
+// Engines are not safe for concurrent use.
+type Engine interface {
+	// Advances the game by one step: 2^jump generations for engines
+	// constructed with a jump, a single generation otherwise.
+	Step()
+	// Advances the game by `n` steps.
+	StepN(n int)
+	// Reports whether the cell at `x`, `y` is alive.
+	Get(x, y int) bool
+	// Brings the cell at `x`, `y` to life or kills it. Reports false if the
+	// cell is outside the board.
+	Set(x, y int, isAlive bool) bool
+	// Returns the number of rows and columns of the board, zero for engines
+	// running unbounded universes.
+	Bounds() (int, int)
+	// Returns the number of live cells.
+	Population() int
+	// Returns the live cells.
+	Snapshot() Snapshot
+	// Replaces the state of the game with `snapshot`.
+	Restore(snapshot Snapshot)
+	// Returns an independent copy of the engine.
+	Clone() Engine
+}
+
+// The settings an engine is constructed with.
+type EngineConfig struct {
+	// The dimensions of the board, ignored by unbounded engines.
+	Rows, Cols int
+	Topology   Topology
+	Rule       Rule
+	// Every step advances the game by 2^Jump generations.
+	Jump uint
+}
+
+// An EngineFactory describes an engine and constructs instances of it.
+type EngineFactory struct {
+	// The topologies the engine supports, the first one is its default.
+	Topologies []Topology
+	// Whether the engine supports jumping more than one generation per step.
+	Jumps bool
+	// Constructs an engine with no live cells.
+	New func(config EngineConfig) Engine
+}
+
+// The registered engines, guarded by enginesMutex as games may be constructed
+// from many goroutines.
+var enginesMutex sync.RWMutex
+var engines = map[string]EngineFactory{
+	DenseEngine: {
+		Topologies: []Topology{Bounded, Torus, KleinBottle, CrossSurface},
+		New:        newDenseEngine,
+	},
+	SparseEngine: {
+		Topologies: []Topology{Unbounded},
+		New:        newSparseEngine,
+	},
+	HashLifeEngine: {
+		Topologies: []Topology{Unbounded},
+		Jumps:      true,
+		New:        newHashLifeEngine,
+	},
+	PackedEngine: {
+		Topologies: []Topology{Bounded, Torus},
+		New:        newPackedEngine,
+	},
+}
+
+// Makes an engine available under `name`, replacing any engine previously
+// registered under it. Engines must be registered before games are
+// constructed, e.g. from an init function.
+func RegisterEngine(name string, factory EngineFactory) {
+	enginesMutex.Lock()
+	defer enginesMutex.Unlock()
+	engines[name] = factory
+}
+
+// Returns the factory of the engine registered under `name`.
+func lookupEngine(name string) (EngineFactory, bool) {
+	enginesMutex.RLock()
+	defer enginesMutex.RUnlock()
+	factory, found := engines[name]
+	return factory, found
+}
+
+// Returns the names of the registered engines in alphabetical order.
+func Engines() []string {
+	enginesMutex.RLock()
+	defer enginesMutex.RUnlock()
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func unknownEngine(name string) error {
+	return errors.New("unknown engine " + name + ", available engines are " +
+		strings.Join(Engines(), ", "))
+}
+
+// Constructs an engine with no live cells by the name it was registered under.
+// Fails if the engine does not exist or does not support the configuration.
+func NewEngine(name string, config EngineConfig) (Engine, error) {
+	factory, found := lookupEngine(name)
+	if !found {
+		return nil, unknownEngine(name)
+	}
+	if !slices.Contains(factory.Topologies, config.Topology) {
+		return nil, errors.New("the " + name + " engine does not support the " +
+			config.Topology.String() + " topology")
+	}
+	if config.Jump > 0 && !factory.Jumps {
+		return nil, errors.New("the " + name + " engine does not support jumping")
+	}
+	if config.Topology != Unbounded && (config.Rows <= 0 || config.Cols <= 0) {
+		return nil, errors.New("the board must have positive dimensions")
+	}
+	return factory.New(config), nil
+}
+
+// Returns the smallest rectangle containing all the `cells` given by its top
+// left and bottom right cells. `cells` must not be empty.
+func boundingBox(cells []Cell) (int, int, int, int) {
+	minX, minY := cells[0].X, cells[0].Y
+	maxX, maxY := minX, minY
+	for _, c := range cells {
+		minX, maxX = min(minX, c.X), max(maxX, c.X)
+		minY, maxY = min(minY, c.Y), max(maxY, c.Y)
+	}
+	return minX, minY, maxX, maxY
+}
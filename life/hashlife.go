@@ -0,0 +1,312 @@
+package life
+
+// The number of canonical nodes a HashLife universe may hold before its caches
+// are flushed.
+const maxHashLifeNodes = 1 << 22
+
+// The largest supported jump, larger jumps would overflow the coordinates.
+const MaxJump = 48
+
+// A node of the HashLife quadtree. A node of level `n` is a square of 2^n×2^n
+// cells; leaves (level 0) are single cells. Nodes are immutable and
+// canonical: equal squares are represented by the same node.
+type hashNode struct {
+	nw, ne, sw, se *hashNode
+	level          uint
+	population     int
+}
+
+type quadrants [4]*hashNode
+
+type memoKey struct {
+	node *hashNode
+	jump uint
+}
+
+// A HashLife engine runs an unbounded universe stored as a quadtree whose
+// nodes are shared and whose futures are memoized, which lets it advance
+// 2^jump generations in a single step.
+// The root node is centered at the origin.
+type hashLifeEngine struct {
+	root      *hashNode
+	nodes     map[quadrants]*hashNode
+	memo      map[memoKey]*hashNode
+	empty     []*hashNode
+	deadLeaf  *hashNode
+	aliveLeaf *hashNode
+	rule      Rule
+	jump      uint
+}
+
+func newHashLifeEngine(config EngineConfig) Engine {
+	e := &hashLifeEngine{rule: config.Rule, jump: config.Jump}
+	e.reset()
+	e.root = e.emptyNode(3)
+	return e
+}
+
+// Drops all canonical nodes and memoized results.
+func (e *hashLifeEngine) reset() {
+	e.nodes = make(map[quadrants]*hashNode)
+	e.memo = make(map[memoKey]*hashNode)
+	e.deadLeaf = &hashNode{}
+	e.aliveLeaf = &hashNode{population: 1}
+	e.empty = []*hashNode{e.deadLeaf}
+}
+
+func (e *hashLifeEngine) join(nw, ne, sw, se *hashNode) *hashNode {
+	key := quadrants{nw, ne, sw, se}
+	if n, found := e.nodes[key]; found {
+		return n
+	}
+	n := &hashNode{
+		nw: nw, ne: ne, sw: sw, se: se,
+		level:      nw.level + 1,
+		population: nw.population + ne.population + sw.population + se.population,
+	}
+	e.nodes[key] = n
+	return n
+}
+
+func (e *hashLifeEngine) emptyNode(level uint) *hashNode {
+	for uint(len(e.empty)) <= level {
+		blank := e.empty[len(e.empty)-1]
+		e.empty = append(e.empty, e.join(blank, blank, blank, blank))
+	}
+	return e.empty[level]
+}
+
+// Returns the node one level higher with `n` in its center.
+func (e *hashLifeEngine) expand(n *hashNode) *hashNode {
+	blank := e.emptyNode(n.level - 1)
+	return e.join(
+		e.join(blank, blank, blank, n.nw),
+		e.join(blank, blank, n.ne, blank),
+		e.join(blank, n.sw, blank, blank),
+		e.join(n.se, blank, blank, blank),
+	)
+}
+
+func (e *hashLifeEngine) center(n *hashNode) *hashNode {
+	return e.join(n.nw.se, n.ne.sw, n.sw.ne, n.se.nw)
+}
+
+// The cells of the root span [-half, half) in both directions.
+func (e *hashLifeEngine) half() int {
+	return 1 << (e.root.level - 1)
+}
+
+func (e *hashLifeEngine) contains(x, y int) bool {
+	half := e.half()
+	return x >= -half && x < half && y >= -half && y < half
+}
+
+func getCell(n *hashNode, x, y int) bool {
+	for n.level > 0 {
+		if n.population == 0 {
+			return false
+		}
+		half := 1 << (n.level - 1)
+		switch {
+		case x < half && y < half:
+			n = n.nw
+		case x < half:
+			n, y = n.ne, y-half
+		case y < half:
+			n, x = n.sw, x-half
+		default:
+			n, x, y = n.se, x-half, y-half
+		}
+	}
+	return n.population == 1
+}
+
+func (e *hashLifeEngine) setCell(n *hashNode, x, y int, isAlive bool) *hashNode {
+	if n.level == 0 {
+		if isAlive {
+			return e.aliveLeaf
+		}
+		return e.deadLeaf
+	}
+	half := 1 << (n.level - 1)
+	switch {
+	case x < half && y < half:
+		return e.join(e.setCell(n.nw, x, y, isAlive), n.ne, n.sw, n.se)
+	case x < half:
+		return e.join(n.nw, e.setCell(n.ne, x, y-half, isAlive), n.sw, n.se)
+	case y < half:
+		return e.join(n.nw, n.ne, e.setCell(n.sw, x-half, y, isAlive), n.se)
+	default:
+		return e.join(n.nw, n.ne, n.sw, e.setCell(n.se, x-half, y-half, isAlive))
+	}
+}
+
+func (e *hashLifeEngine) Get(x, y int) bool {
+	if !e.contains(x, y) {
+		return false
+	}
+	half := e.half()
+	return getCell(e.root, x+half, y+half)
+}
+
+func (e *hashLifeEngine) Set(x, y int, isAlive bool) bool {
+	for !e.contains(x, y) {
+		e.root = e.expand(e.root)
+	}
+	half := e.half()
+	e.root = e.setCell(e.root, x+half, y+half, isAlive)
+	return true
+}
+
+// Advances the 2×2 center of a level 2 node by one generation.
+func (e *hashLifeEngine) baseSuccessor(n *hashNode) *hashNode {
+	var grid [4][4]bool
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			grid[x][y] = getCell(n, x, y)
+		}
+	}
+	var next [4]*hashNode
+	for idx := range next {
+		x, y := 1+idx/2, 1+idx%2
+		var count uint8 = 0
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if (dx != 0 || dy != 0) && grid[x+dx][y+dy] {
+					count++
+				}
+			}
+		}
+		next[idx] = e.deadLeaf
+		if e.rule.livesOn(grid[x][y], count) {
+			next[idx] = e.aliveLeaf
+		}
+	}
+	return e.join(next[0], next[1], next[2], next[3])
+}
+
+// Returns the center of `n` (one level lower) advanced by 2^jump
+// generations, `jump` being capped at the level of `n` minus two.
+func (e *hashLifeEngine) successor(n *hashNode, jump uint) *hashNode {
+	if n.population == 0 {
+		return e.emptyNode(n.level - 1)
+	}
+	jump = min(jump, n.level-2)
+	key := memoKey{n, jump}
+	if result, found := e.memo[key]; found {
+		return result
+	}
+
+	var result *hashNode
+	if n.level == 2 {
+		result = e.baseSuccessor(n)
+	} else {
+		c1 := e.successor(n.nw, jump)
+		c2 := e.successor(e.join(n.nw.ne, n.ne.nw, n.nw.se, n.ne.sw), jump)
+		c3 := e.successor(n.ne, jump)
+		c4 := e.successor(e.join(n.nw.sw, n.nw.se, n.sw.nw, n.sw.ne), jump)
+		c5 := e.successor(e.center(n), jump)
+		c6 := e.successor(e.join(n.ne.sw, n.ne.se, n.se.nw, n.se.ne), jump)
+		c7 := e.successor(n.sw, jump)
+		c8 := e.successor(e.join(n.sw.ne, n.se.nw, n.sw.se, n.se.sw), jump)
+		c9 := e.successor(n.se, jump)
+		if jump < n.level-2 {
+			result = e.join(
+				e.join(c1.se, c2.sw, c4.ne, c5.nw),
+				e.join(c2.se, c3.sw, c5.ne, c6.nw),
+				e.join(c4.se, c5.sw, c7.ne, c8.nw),
+				e.join(c5.se, c6.sw, c8.ne, c9.nw),
+			)
+		} else {
+			result = e.join(
+				e.successor(e.join(c1, c2, c4, c5), jump),
+				e.successor(e.join(c2, c3, c5, c6), jump),
+				e.successor(e.join(c4, c5, c7, c8), jump),
+				e.successor(e.join(c5, c6, c8, c9), jump),
+			)
+		}
+	}
+	e.memo[key] = result
+	return result
+}
+
+// Advances the universe by 2^jump generations. The root is grown until the
+// pattern is far enough from its edges for the result to contain all of it.
+func (e *hashLifeEngine) Step() {
+	for e.root.level < e.jump+2 || e.center(e.root).population != e.root.population {
+		e.root = e.expand(e.root)
+	}
+	e.root = e.successor(e.expand(e.root), e.jump)
+	if len(e.nodes) > maxHashLifeNodes {
+		e.collect()
+	}
+}
+
+func (e *hashLifeEngine) StepN(n int) {
+	for i := 0; i < n; i++ {
+		e.Step()
+	}
+}
+
+// Drops the caches, keeping only the nodes of the current root.
+func (e *hashLifeEngine) collect() {
+	root := e.root
+	e.reset()
+	e.root = e.intern(root)
+}
+
+func (e *hashLifeEngine) intern(n *hashNode) *hashNode {
+	if n.level == 0 {
+		if n.population == 1 {
+			return e.aliveLeaf
+		}
+		return e.deadLeaf
+	}
+	if n.population == 0 {
+		return e.emptyNode(n.level)
+	}
+	return e.join(e.intern(n.nw), e.intern(n.ne), e.intern(n.sw), e.intern(n.se))
+}
+
+func collectCells(n *hashNode, x, y int, cells []Cell) []Cell {
+	if n.population == 0 {
+		return cells
+	}
+	if n.level == 0 {
+		return append(cells, Cell{x, y})
+	}
+	half := 1 << (n.level - 1)
+	cells = collectCells(n.nw, x, y, cells)
+	cells = collectCells(n.ne, x, y+half, cells)
+	cells = collectCells(n.sw, x+half, y, cells)
+	return collectCells(n.se, x+half, y+half, cells)
+}
+
+func (e *hashLifeEngine) Bounds() (int, int) {
+	return 0, 0
+}
+
+func (e *hashLifeEngine) Population() int {
+	return e.root.population
+}
+
+func (e *hashLifeEngine) Snapshot() Snapshot {
+	half := e.half()
+	return newSnapshot(collectCells(e.root, -half, -half, make([]Cell, 0, e.root.population)))
+}
+
+func (e *hashLifeEngine) Restore(snapshot Snapshot) {
+	e.root = e.emptyNode(3)
+	for _, c := range snapshot {
+		e.Set(c.X, c.Y, true)
+	}
+}
+
+// Nodes are shared between the universes which use them, so the copy gets
+// its own.
+func (e *hashLifeEngine) Clone() Engine {
+	c := &hashLifeEngine{rule: e.rule, jump: e.jump}
+	c.reset()
+	c.root = c.intern(e.root)
+	return c
+}
@@ -0,0 +1,160 @@
+package life
+
+import (
+	"slices"
+	"sort"
+)
+
+// The number of steps between full snapshots in the history of a game. The
+// steps in between are stored as the cells which changed since the previous
+// step.
+const keyframeInterval = 32
+
+type historyEntry struct {
+	generation     uint64
+	population     int
+	births, deaths int
+	// Whether the entry stores the whole state, which is the case for every
+	// keyframeInterval-th entry.
+	isKeyframe bool
+	keyframe   Snapshot
+	// The cells born or died since the previous entry, unless a keyframe.
+	toggled []Cell
+}
+
+// A history is a ring of the most recent states of a game, the oldest of
+// which is always a keyframe. The cursor is the entry of the current state,
+// which precedes the newest entry after rewinding; advancing the game from
+// there discards the entries past the cursor.
+type history struct {
+	entries []historyEntry
+	oldest  int
+	count   int
+	cursor  int
+	// The state of the entry under the cursor.
+	current Snapshot
+}
+
+func newHistory(depth int) *history {
+	return &history{entries: make([]historyEntry, depth)}
+}
+
+// Returns a copy of the history sharing the immutable states of its entries.
+func (h *history) clone() *history {
+	clone := *h
+	clone.entries = slices.Clone(h.entries)
+	return &clone
+}
+
+// Returns the `i`-th oldest entry.
+func (h *history) at(i int) *historyEntry {
+	return &h.entries[(h.oldest+i)%len(h.entries)]
+}
+
+// Reconstructs the state of the `i`-th oldest entry by applying the changes
+// since the closest keyframe preceding it.
+func (h *history) state(i int) Snapshot {
+	k := i
+	for !h.at(k).isKeyframe {
+		k--
+	}
+	if k == i {
+		return h.at(i).keyframe
+	}
+	cells := make(map[Cell]struct{}, h.at(i).population)
+	for _, c := range h.at(k).keyframe {
+		cells[c] = struct{}{}
+	}
+	for k++; k <= i; k++ {
+		for _, c := range h.at(k).toggled {
+			if _, found := cells[c]; found {
+				delete(cells, c)
+			} else {
+				cells[c] = struct{}{}
+			}
+		}
+	}
+	state := make([]Cell, 0, len(cells))
+	for c := range cells {
+		state = append(state, c)
+	}
+	return newSnapshot(state)
+}
+
+// Appends `state` at `generation`, reached by `births` and `deaths`, after the
+// cursor, evicting the oldest entry if the history is full.
+func (h *history) record(generation uint64, state Snapshot, births, deaths int) {
+	if h.count > 0 {
+		h.count = h.cursor + 1
+	}
+	if h.count == len(h.entries) {
+		h.evict()
+	}
+	entry := historyEntry{
+		generation: generation,
+		population: len(state),
+		births:     births,
+		deaths:     deaths,
+	}
+	sinceKeyframe := 0
+	for i := h.count - 1; i >= 0 && !h.at(i).isKeyframe; i-- {
+		sinceKeyframe++
+	}
+	if h.count == 0 || sinceKeyframe+1 >= keyframeInterval {
+		entry.isKeyframe, entry.keyframe = true, state
+	} else {
+		compareSnapshots(h.current, state, func(c Cell, _ bool) {
+			entry.toggled = append(entry.toggled, c)
+		})
+	}
+	*h.at(h.count) = entry
+	h.cursor = h.count
+	h.count++
+	h.current = state
+}
+
+// Replaces the state of the entry under the cursor with `state`, discarding
+// the entries past it.
+func (h *history) amend(state Snapshot) {
+	h.count = h.cursor + 1
+	entry := h.at(h.cursor)
+	entry.population = len(state)
+	if entry.isKeyframe {
+		entry.keyframe = state
+	} else {
+		entry.toggled = nil
+		compareSnapshots(h.state(h.cursor-1), state, func(c Cell, _ bool) {
+			entry.toggled = append(entry.toggled, c)
+		})
+	}
+	h.current = state
+}
+
+// Drops the oldest entry, turning the entry following it into a keyframe.
+func (h *history) evict() {
+	if h.count > 1 && !h.at(1).isKeyframe {
+		next := h.at(1)
+		next.isKeyframe, next.keyframe = true, h.state(1)
+		next.toggled = nil
+	}
+	*h.at(0) = historyEntry{}
+	h.oldest = (h.oldest + 1) % len(h.entries)
+	h.count--
+	h.cursor--
+}
+
+// Returns the index of the entry at `generation`, reporting false if there is
+// none.
+func (h *history) find(generation uint64) (int, bool) {
+	i := sort.Search(h.count, func(i int) bool {
+		return h.at(i).generation >= generation
+	})
+	return i, i < h.count && h.at(i).generation == generation
+}
+
+// Moves the cursor to the `i`-th oldest entry and returns its state.
+func (h *history) seek(i int) Snapshot {
+	h.cursor = i
+	h.current = h.state(i)
+	return h.current
+}
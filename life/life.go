@@ -2,11 +2,12 @@
 package life
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -17,218 +18,508 @@ const alive boardSymbol = '*'
 const dead boardSymbol = ' '
 const configFolder = "predefined_configs"
 
-// The game is represented by its current state.
-// Additionally, the dimensions of the board are recorded as well as a
-// temporary state used for computing the next generation and the beginning
-// state is recorded so it can be restored.
+// The number of dead cells added on each side of patterns which do not specify
+// the dimensions of the whole board, unless configured otherwise.
+const DefaultMargin = 8
+
+// The maximum number of rows and columns shown by Printable and
+// PrintableRegion.
+const MaxViewport = 256
+
+// The largest number of rows and columns of a board.
+const MaxBoardSize = 4096
+
+// The largest number of steps a game is advanced by at once.
+const MaxSteps = 10000
+
+// Fails if a board with `rows` rows and `cols` columns would be too large.
+func checkBoardSize(rows, cols int) error {
+	if rows > MaxBoardSize || cols > MaxBoardSize {
+		return fmt.Errorf("the %dx%d board is larger than the maximum of %dx%d",
+			rows, cols, MaxBoardSize, MaxBoardSize)
+	}
+	return nil
+}
+
+// The game is represented by its current state, the rule it evolves by and
+// the topology of the board.
+// Additionally, the dimensions of the board are recorded and the beginning
+// state is recorded so it can be restored. Unbounded universes have no board,
+// their dimensions are those of the starting configuration.
+// The state is stored and advanced by an engine, see Engine.
+// Every call to NextGeneration advances the game by 2^jump generations. The
+// game keeps count of the generations, tracks its population and watches for
+// the state settling. Games constructed WithHistory remember their recent
+// states and can be moved back and forth between them.
+// A game is not safe for concurrent use, its users synchronize access to it;
+// Clone returns an independent copy.
 type Life struct {
-	startConfig  [][]boardSymbol
-	currentState [][]boardSymbol
-	tempState    [][]boardSymbol
+	startConfig  Engine
+	currentState Engine
+	engine       string
 	dimX, dimY   int
+	startDimX    int
+	startDimY    int
+	rule         Rule
+	topology     Topology
+	jump         uint
+	generation   uint64
+	stats        statsTracker
+	detector     *detector
+	historyDepth int
+	history      *history
+}
+
+// An Option customizes the construction of a game.
+type Option func(*options)
+
+type options struct {
+	margin     int
+	rule       *Rule
+	topology   *Topology
+	engine     string
+	jump       uint
+	history    int
+	transforms []Transform
+}
+
+// Surrounds patterns which do not specify the dimensions of the whole board
+// with `margin` dead cells on each side.
+func WithMargin(margin int) Option {
+	return func(o *options) {
+		o.margin = margin
+	}
+}
+
+// Makes the game evolve by `rule` regardless of the rule specified by the
+// configuration.
+func WithRule(rule Rule) Option {
+	return func(o *options) {
+		o.rule = &rule
+	}
+}
+
+// Connects the edges of the board according to `topology`. Boards are
+// bounded by default.
+func WithTopology(topology Topology) Option {
+	return func(o *options) {
+		o.topology = &topology
+	}
+}
+
+// Runs the game on the engine registered under the name `engine`. Unless a
+// topology is given, the game gets the default topology of the engine. By
+// default games run on the dense engine, or on the sparse engine if the
+// topology is Unbounded.
+func WithEngine(engine string) Option {
+	return func(o *options) {
+		o.engine = engine
+	}
+}
+
+// Makes every generation step advance the game by 2^jump generations. Not
+// all engines support jumping.
+func WithJump(jump uint) Option {
+	return func(o *options) {
+		o.jump = jump
+	}
+}
+
+// Makes the game remember its states during the last `depth` steps so it can
+// be rewound to them. Games keep no history by default.
+func WithHistory(depth int) Option {
+	return func(o *options) {
+		o.history = depth
+	}
+}
+
+// Applies `transforms` in order to the board described by the configuration,
+// after surrounding it with the margin.
+func WithTransforms(transforms ...Transform) Option {
+	return func(o *options) {
+		o.transforms = append(o.transforms, transforms...)
+	}
 }
 
-func deep2DCopy(x, y int, board [][]boardSymbol) [][]boardSymbol {
-	res := make([][]boardSymbol, x)
-	for i := range board {
-		res[i] = make([]boardSymbol, y)
-		copy(res[i], board[i])
+// Fills in the defaults for the engine and the topology if they were not
+// specified.
+func (o *options) resolve() error {
+	if o.engine == "" {
+		o.engine = DenseEngine
+		if o.topology != nil && *o.topology == Unbounded {
+			o.engine = SparseEngine
+		}
+	}
+	factory, found := lookupEngine(o.engine)
+	if !found {
+		return unknownEngine(o.engine)
+	}
+	if o.topology == nil {
+		topology := factory.Topologies[0]
+		o.topology = &topology
+	}
+	if *o.topology == Unbounded && o.rule != nil && o.rule.birth[0] {
+		return errors.New("rules with B0 are not supported in unbounded universes")
 	}
-	return res
+	if o.jump > MaxJump {
+		return errors.New("the jump must not exceed " + strconv.Itoa(MaxJump))
+	}
+	return nil
 }
 
 // Constructs a new game by reading the provided `config`.
-// Configurations containing lines longer than 65536 characters are not supported.
-func NewLife(config string) (*Life, error) {
-	l := new(Life)
+// See parseConfig for the supported configuration formats. Configurations
+// starting with `random:` are not read but describe a random soup, see
+// parseSoup.
+// The game evolves by the rule given as an option, by the rule specified in
+// the configuration or by Conway's rule, in that order of precedence.
+func NewLife(config string, opts ...Option) (*Life, error) {
+	if strings.HasPrefix(config, soupPrefix) {
+		p, err := parseSoup(config)
+		if err != nil {
+			return nil, err
+		}
+		return newLifeFromConfig(p, opts...)
+	}
+	configFile, err := openConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	defer configFile.Close()
+	return newLife(configFile, opts...)
+}
 
+// Opens the predefined configuration `config`.
+// Fails if the name of the configuration refers to a file outside of the
+// folder of predefined configurations.
+func openConfig(config string) (*os.File, error) {
+	if strings.ContainsAny(config, `/\`) || strings.Contains(config, "..") {
+		return nil, errors.New("invalid configuration name " + config)
+	}
 	configFile, err := os.Open(path.Join(configFolder, config))
 	if err != nil {
 		return nil, errors.New("the configuration you specified does not exist")
 	}
-	defer configFile.Close()
+	return configFile, nil
+}
 
-	scanner := bufio.NewScanner(configFile)
-	scanner.Scan()
-	dimensions := strings.Split(scanner.Text(), " ")
-	l.dimX, _ = strconv.Atoi(dimensions[0])
-	l.dimY, _ = strconv.Atoi(dimensions[1])
-
-	l.startConfig = make([][]boardSymbol, l.dimX)
-	curRow := 0
-	for scanner.Scan() {
-		row := scanner.Text()
-		l.startConfig[curRow] = make([]boardSymbol, l.dimY)
-		for curCol, char := range row {
-			var write boardSymbol
-			if char == '-' {
-				write = dead
-			} else if char == '*' {
-				write = alive
-			} else {
-				return nil, errors.New("infalid config")
-			}
-			l.startConfig[curRow][curCol] = write
+func newLife(config io.Reader, opts ...Option) (*Life, error) {
+	p, err := parseConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return newLifeFromConfig(p, opts...)
+}
+
+// Constructs a game from the configuration `p`, applying `opts`.
+func newLifeFromConfig(p *pattern, opts ...Option) (*Life, error) {
+	o := options{margin: DefaultMargin}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.margin < 0 {
+		return nil, errors.New("the margin must not be negative")
+	}
+	if o.margin > MaxBoardSize {
+		return nil, errors.New("the margin must not exceed " + strconv.Itoa(MaxBoardSize))
+	}
+	if o.history < 0 {
+		return nil, errors.New("the history depth must not be negative")
+	}
+	if o.rule == nil && p.rule != "" {
+		rule, err := ParseRule(p.rule)
+		if err != nil {
+			return nil, err
 		}
-		curRow++
+		o.rule = &rule
 	}
-	if curRow != l.dimX {
-		return nil, errors.New("infalid config")
+	if err := o.resolve(); err != nil {
+		return nil, err
 	}
-
-	l.currentState = deep2DCopy(l.dimX, l.dimY, l.startConfig)
-	l.tempState = deep2DCopy(l.dimX, l.dimY, l.startConfig)
-	return l, nil
+	return newLifeFromPattern(p, o)
 }
 
-// Returns a string representing the current state of the game.
-func (l *Life) Printable() string {
-	board := ""
-	for _, row := range l.currentState {
-		for _, symbol := range row {
-			board += fmt.Sprintf(" %c ", symbol)
+// Constructs a game from `p` using resolved options.
+func newLifeFromPattern(p *pattern, o options) (*Life, error) {
+	l := new(Life)
+	l.rule = Conway
+	if o.rule != nil {
+		l.rule = *o.rule
+	}
+	l.topology = *o.topology
+	l.engine = o.engine
+	l.jump = o.jump
+	l.historyDepth = o.history
+	if !p.hasBoard {
+		p.pad(o.margin)
+	}
+	if err := checkBoardSize(p.rows, p.cols); err != nil {
+		return nil, err
+	}
+	for _, transform := range o.transforms {
+		if err := transform(p); err != nil {
+			return nil, err
 		}
-		board += "\n"
 	}
-	return board
+	if err := checkBoardSize(p.rows, p.cols); err != nil {
+		return nil, err
+	}
+	l.dimX, l.dimY = p.rows, p.cols
+	l.startDimX, l.startDimY = l.dimX, l.dimY
+
+	var err error
+	l.startConfig, err = NewEngine(o.engine, EngineConfig{
+		Rows:     l.dimX,
+		Cols:     l.dimY,
+		Topology: l.topology,
+		Rule:     l.rule,
+		Jump:     l.jump,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range p.cells {
+		l.startConfig.Set(c.X, c.Y, true)
+	}
+	l.Reset()
+	return l, nil
 }
 
-func (l *Life) getAliveNeighboursCnt(x, y int) uint8 {
-	var count uint8 = 0
-	var xUpperBoarder bool = x == 0
-	var xLowerBoarder bool = x == l.dimX-1
-	var yLeftBoarder bool = y == 0
-	var yRightBoarder bool = y == l.dimY-1
+// Restores the beginning state of the game and starts counting generations
+// and keeping statistics anew.
+func (l *Life) Reset() {
+	l.currentState = l.startConfig.Clone()
+	l.dimX, l.dimY = l.startDimX, l.startDimY
+	l.generation = 0
+	l.stats = statsTracker{}
+	l.stats.record(l.currentState.Population())
+	l.detector = newDetector()
+	l.observe()
+	l.restartHistory()
+}
 
-	if !xUpperBoarder {
-		if !yLeftBoarder && l.currentState[x-1][y-1] == alive {
-			count++
-		}
-		if l.currentState[x-1][y] == alive {
-			count++
-		}
-		if !yRightBoarder && l.currentState[x-1][y+1] == alive {
-			count++
-		}
+// Discards the history of the game, which begins anew at the current state.
+func (l *Life) restartHistory() {
+	if l.historyDepth > 0 {
+		l.history = newHistory(l.historyDepth + 1)
+		l.history.record(l.generation, l.currentState.Snapshot(),
+			l.stats.births, l.stats.deaths)
 	}
-	if !xLowerBoarder {
-		if !yLeftBoarder && l.currentState[x+1][y-1] == alive {
-			count++
-		}
-		if l.currentState[x+1][y] == alive {
-			count++
-		}
-		if !yRightBoarder && l.currentState[x+1][y+1] == alive {
-			count++
-		}
+}
+
+// Returns an independent copy of the game, including its statistics, status
+// and history.
+func (l *Life) Clone() *Life {
+	clone := *l
+	clone.startConfig = l.startConfig.Clone()
+	clone.currentState = l.currentState.Clone()
+	clone.stats.history = slices.Clone(l.stats.history)
+	clone.detector = l.detector.clone()
+	if l.history != nil {
+		clone.history = l.history.clone()
 	}
-	if !yLeftBoarder && l.currentState[x][y-1] == alive {
-		count++
+	return &clone
+}
+
+// Returns the rule the game evolves by.
+func (l *Life) Rule() Rule {
+	return l.rule
+}
+
+// Returns the topology of the board.
+func (l *Life) Topology() Topology {
+	return l.topology
+}
+
+// Returns the name of the engine running the game.
+func (l *Life) EngineName() string {
+	return l.engine
+}
+
+// Returns the number of generations every call to NextGeneration advances
+// the game by.
+func (l *Life) GenerationsPerStep() uint64 {
+	return 1 << l.jump
+}
+
+// Returns whether the game has died out or become periodic and since when.
+func (l *Life) Status() Status {
+	return l.detector.status
+}
+
+// Records the current state with the detector, updating the status.
+func (l *Life) observe() {
+	l.detector.observe(l.currentState, l.topology == Unbounded, l.generation,
+		l.GenerationsPerStep(), l.singleGenerations)
+}
+
+// Returns a copy of the current state which advances a single generation per
+// step, whatever the jump of the game.
+func (l *Life) singleGenerations() Engine {
+	engine, _ := NewEngine(l.engine, EngineConfig{
+		Rows:     l.dimX,
+		Cols:     l.dimY,
+		Topology: l.topology,
+		Rule:     l.rule,
+	})
+	engine.Restore(l.currentState.Snapshot())
+	return engine
+}
+
+// Returns the number of generations the game has advanced by.
+func (l *Life) Generation() uint64 {
+	return l.generation
+}
+
+// Returns the number of live cells.
+func (l *Life) Population() int {
+	return l.currentState.Population()
+}
+
+// Returns the current generation, population and bounding box of the game,
+// the changes made by the last step and the recent populations.
+func (l *Life) Stats() Stats {
+	stats := Stats{
+		Generation: l.generation,
+		Population: l.currentState.Population(),
+		Births:     l.stats.births,
+		Deaths:     l.stats.deaths,
+		History:    l.stats.populations(),
 	}
-	if !yRightBoarder && l.currentState[x][y+1] == alive {
-		count++
+	if stats.Population > 0 {
+		minX, minY, maxX, maxY := boundingBox(l.currentState.Snapshot())
+		stats.TopLeft, stats.BottomRight = Cell{minX, minY}, Cell{maxX, maxY}
 	}
-
-	return count
+	return stats
 }
 
-func (l *Life) livesOn(x, y int) bool {
-	aliveNeighboursCnt := l.getAliveNeighboursCnt(x, y)
-	if l.currentState[x][y] == alive {
-		return aliveNeighboursCnt == 2 || aliveNeighboursCnt == 3
-	} else {
-		return aliveNeighboursCnt == 3
+// Returns a string representing the current state of the game.
+// Boards are shown whole, unbounded universes are shown through a viewport
+// following the bounding box of the live cells.
+func (l *Life) Printable() string {
+	if l.topology != Unbounded {
+		return l.PrintableRegion(0, 0, l.dimX, l.dimY)
 	}
+	cells := l.currentState.Snapshot()
+	if len(cells) == 0 {
+		return l.PrintableRegion(0, 0, l.dimX, l.dimY)
+	}
+	minX, minY, maxX, maxY := boundingBox(cells)
+	return l.PrintableRegion(minX-1, minY-1, maxX-minX+3, maxY-minY+3)
 }
 
-// Computes the next generation of the game and updates the state.
-func (l *Life) NextGeneration() {
-	for x, row := range l.currentState {
-		for y, _ := range row {
-			if l.livesOn(x, y) {
-				l.tempState[x][y] = alive
-			} else {
-				l.tempState[x][y] = dead
+// Returns a string representing the `rows`×`cols` region of the game whose top
+// left cell is at `x`, `y`. The region is cut to at most MaxViewport rows and
+// columns.
+func (l *Life) PrintableRegion(x, y, rows, cols int) string {
+	rows, cols = min(rows, MaxViewport), min(cols, MaxViewport)
+	var board strings.Builder
+	for row := x; row < x+rows; row++ {
+		for col := y; col < y+cols; col++ {
+			symbol := dead
+			if l.currentState.Get(row, col) {
+				symbol = alive
 			}
+			board.WriteString(fmt.Sprintf(" %c ", symbol))
 		}
+		board.WriteString("\n")
 	}
-	tmp := l.currentState
-	l.currentState = l.tempState
-	l.tempState = tmp
+	return board.String()
 }
 
-func isNum(char rune) bool {
-	return char >= '0' && char <= '9'
+// Computes the next generation of the game and updates the state. Games
+// constructed with WithJump skip ahead 2^jump generations instead.
+func (l *Life) NextGeneration() {
+	l.stats.step(l.currentState)
+	l.generation += l.GenerationsPerStep()
+	l.observe()
+	if l.history != nil {
+		l.history.record(l.generation, l.currentState.Snapshot(),
+			l.stats.births, l.stats.deaths)
+	}
 }
 
-func decodeInt(line string) (int, int) {
-	read := 0
-	for _, char := range line {
-		if !isNum(char) {
-			break
-		}
-		read++
-	}
-	res, _ := strconv.Atoi(line[:read])
-	if res == 0 {
-		res++
+// Returns the generations of the oldest and the newest state in the history
+// of the game. Reports false if the game keeps no history.
+func (l *Life) HistoryRange() (uint64, uint64, bool) {
+	if l.history == nil {
+		return 0, 0, false
 	}
-	return res, read
+	return l.history.at(0).generation, l.history.at(l.history.count - 1).generation, true
 }
 
-func decodeConfig(path string) *Life {
-	l := new(Life)
+// Moves the game back by `steps` steps.
+// Fails if the game keeps no history or the history does not reach that far.
+func (l *Life) Rewind(steps int) error {
+	if l.history == nil {
+		return errors.New("the game keeps no history")
+	}
+	if steps < 0 || steps > l.history.cursor {
+		return fmt.Errorf("the game can only be rewound by up to %d steps", l.history.cursor)
+	}
+	l.restore(l.history.cursor - steps)
+	return nil
+}
 
-	config, err := os.Open(path)
+// Moves the game to `generation`. Generations past the newest state in the
+// history are computed, up to MaxSteps steps past it.
+// Fails if the game keeps no history, the generation is older than the
+// history, too far past it or it is skipped by games advancing several
+// generations per step.
+func (l *Life) Seek(generation uint64) error {
+	steps, err := l.SeekHistory(generation)
 	if err != nil {
-		fmt.Println(err)
-		return nil
-	}
-	defer config.Close()
-
-	scanner := bufio.NewScanner(config)
-	scanner.Scan()
-	dimensions := strings.Split(scanner.Text(), " ")
-	l.dimX, _ = strconv.Atoi(dimensions[0])
-	l.dimY, _ = strconv.Atoi(dimensions[1])
-	l.startConfig = make([][]boardSymbol, l.dimX)
-	for i := 0; i < l.dimX; i++ {
-		l.startConfig[i] = make([]boardSymbol, l.dimY)
-	}
-
-	currRow := 0
-	currCol := 0
-	var currSymbol byte
-	for scanner.Scan() {
-		line := scanner.Text()
-		lineLen := len(line)
-		read := 0
-		var write boardSymbol
-		for read < lineLen {
-			num, nextSymbol := decodeInt(line)
-			fmt.Println(num, nextSymbol)
-			currSymbol = line[nextSymbol]
-			read += nextSymbol + 1
-			line = line[nextSymbol+1:]
-			fmt.Println(line)
-			if currSymbol == 'b' {
-				write = dead
-			} else if currSymbol == 'o' {
-				write = alive
-			} else if currSymbol == '$' {
-				currRow++
-				currCol = 0
-				continue
-			} else {
-				break
-			}
-			for i := 0; i < num; i++ {
-				l.startConfig[currRow][currCol] = write
-				currCol++
-			}
+		return err
+	}
+	for ; steps > 0; steps-- {
+		l.NextGeneration()
+	}
+	return nil
+}
+
+// Moves the game to `generation` if it is in the history, or else to the
+// newest state in the history, and returns the number of steps still to be
+// computed to reach `generation`.
+// Fails in the same cases as Seek.
+func (l *Life) SeekHistory(generation uint64) (int, error) {
+	if l.history == nil {
+		return 0, errors.New("the game keeps no history")
+	}
+	if generation%l.GenerationsPerStep() != 0 {
+		return 0, fmt.Errorf("the game advances by %d generations per step, %d is skipped",
+			l.GenerationsPerStep(), generation)
+	}
+	oldest, newest, _ := l.HistoryRange()
+	if generation < oldest {
+		return 0, fmt.Errorf("generation %d is no longer in the history, the oldest is %d",
+			generation, oldest)
+	}
+	if generation > newest {
+		steps := (generation - newest) / l.GenerationsPerStep()
+		if steps > MaxSteps {
+			return 0, fmt.Errorf("generation %d is more than %d steps past the newest, %d",
+				generation, MaxSteps, newest)
 		}
+		l.restore(l.history.count - 1)
+		return int(steps), nil
+	}
+	i, _ := l.history.find(generation)
+	l.restore(i)
+	return 0, nil
+}
+
+// Replaces the current state with the `i`-th oldest state in the history and
+// rebuilds the statistics from it. The status is detected anew.
+func (l *Life) restore(i int) {
+	state := l.history.seek(i)
+	l.currentState.Restore(state)
+	l.generation = l.history.at(i).generation
+
+	l.stats = statsTracker{}
+	for j := max(0, i-PopulationHistory+1); j <= i; j++ {
+		l.stats.record(l.history.at(j).population)
 	}
-	l.currentState = deep2DCopy(l.dimX, l.dimY, l.startConfig)
-	l.tempState = deep2DCopy(l.dimX, l.dimY, l.startConfig)
-	return l
+	l.stats.births, l.stats.deaths = l.history.at(i).births, l.history.at(i).deaths
+	l.detector = newDetector()
+	l.observe()
 }
@@ -0,0 +1,800 @@
+package life
+
+import (
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func parse(config string, t *testing.T) *pattern {
+	p, err := parseConfig(strings.NewReader(config))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return p
+}
+
+func newTestLife(config string, t *testing.T, opts ...Option) *Life {
+	l, err := newLife(strings.NewReader(config), opts...)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return l
+}
+
+func assertCells(p *pattern, expected []Cell, t *testing.T) {
+	if len(p.cells) != len(expected) {
+		t.Fatalf("expected %d cells, got %d: %v", len(expected), len(p.cells), p.cells)
+	}
+	for idx, c := range expected {
+		if p.cells[idx] != c {
+			t.Fatalf("expected cell %v at %d, got %v", c, idx, p.cells[idx])
+		}
+	}
+}
+
+func TestParseGrid(t *testing.T) {
+	p := parse("2 3\n-*-\n**-\n", t)
+	if p.rows != 2 || p.cols != 3 {
+		t.Fatalf("expected 2x3 board, got %dx%d", p.rows, p.cols)
+	}
+	assertCells(p, []Cell{{0, 1}, {1, 0}, {1, 1}}, t)
+}
+
+func TestParseRLE(t *testing.T) {
+	config := "#N Glider\n#C a comment\nx = 3, y = 4, rule = B3/S23\n" +
+		"bo$2b\no$3o\n!ignored"
+	p := parse(config, t)
+	if p.rows != 4 || p.cols != 3 {
+		t.Fatalf("expected 4x3 board, got %dx%d", p.rows, p.cols)
+	}
+	if p.rule != "B3/S23" {
+		t.Fatalf("expected rule B3/S23, got %s", p.rule)
+	}
+	assertCells(p, []Cell{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}, t)
+}
+
+func TestParseRLEGollyRule(t *testing.T) {
+	_, err := parseConfig(strings.NewReader("x = 3, y = 3, rule = B3/S23:T20,10\nbo$2bo$3o!"))
+	expected := "invalid config: the bounded grid :T20,10 of the RLE rule is not supported, " +
+		"use the topology option instead"
+	if err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestParseRLEMultipleRowSkip(t *testing.T) {
+	p := parse("x = 1, y = 4\no3$o!", t)
+	assertCells(p, []Cell{{0, 0}, {3, 0}}, t)
+}
+
+func TestParseRLEWithDimensionsLine(t *testing.T) {
+	p := parse("5 5\n2b3o$bo2bo$o3bo$o2bob$3o!", t)
+	if p.rows != 5 || p.cols != 5 {
+		t.Fatalf("expected 5x5 board, got %dx%d", p.rows, p.cols)
+	}
+	if len(p.cells) != 12 {
+		t.Fatalf("expected 12 live cells, got %d", len(p.cells))
+	}
+}
+
+func TestParseRLEInvalid(t *testing.T) {
+	configs := []string{
+		"x = 3, y = 3\nbo$2bo$3o",
+		"x = 2, y = 2\n3o!",
+		"x = 3, y = 3\nbo$2bq$3o!",
+		"x = 3\nbo!",
+	}
+	for _, config := range configs {
+		if _, err := parseConfig(strings.NewReader(config)); err == nil {
+			t.Fatalf("expected an error for %q", config)
+		}
+	}
+}
+
+func TestParsePlaintext(t *testing.T) {
+	p := parse("!Name: Glider\n!\n.O.\n..O\nOOO\n", t)
+	if p.rows != 3 || p.cols != 3 || p.hasBoard {
+		t.Fatalf("expected 3x3 pattern, got %dx%d", p.rows, p.cols)
+	}
+	assertCells(p, []Cell{{0, 1}, {1, 2}, {2, 0}, {2, 1}, {2, 2}}, t)
+}
+
+func TestParsePlaintextBoundingBox(t *testing.T) {
+	p := parse("!x\n.....\n.....\n..O..\n", t)
+	if p.rows != 1 || p.cols != 1 {
+		t.Fatalf("expected 1x1 pattern, got %dx%d", p.rows, p.cols)
+	}
+	assertCells(p, []Cell{{0, 0}}, t)
+}
+
+func TestParseLife105(t *testing.T) {
+	config := "#Life 1.05\n#D Glider\n#R 23/3\n#P -1 -1\n.*\n..*\n#P 5 5\n*\n"
+	p := parse(config, t)
+	if p.rule != "23/3" {
+		t.Fatalf("expected rule 23/3, got %s", p.rule)
+	}
+	if p.rows != 7 || p.cols != 6 {
+		t.Fatalf("expected 7x6 pattern, got %dx%d", p.rows, p.cols)
+	}
+	assertCells(p, []Cell{{0, 0}, {1, 1}, {6, 5}}, t)
+}
+
+func TestParseLife106(t *testing.T) {
+	p := parse("#Life 1.06\n0 -1\n1 -1\n-1 0\n0 0\n0 1\n", t)
+	if p.rows != 3 || p.cols != 3 {
+		t.Fatalf("expected 3x3 pattern, got %dx%d", p.rows, p.cols)
+	}
+	assertCells(p, []Cell{{0, 1}, {0, 2}, {1, 0}, {1, 1}, {2, 1}}, t)
+}
+
+func TestMargin(t *testing.T) {
+	l := newTestLife("#Life 1.06\n0 0\n", t, WithMargin(2))
+	if l.dimX != 5 || l.dimY != 5 || !l.startConfig.Get(2, 2) {
+		t.Fatalf("expected a 5x5 board with a live center")
+	}
+
+	l = newTestLife("1 1\n*\n", t, WithMargin(2))
+	if l.dimX != 1 || l.dimY != 1 {
+		t.Fatalf("expected the margin not to apply to a LaaS grid")
+	}
+
+	config := strings.NewReader("#Life 1.06\n0 0\n")
+	if _, err := newLife(config, WithMargin(MaxBoardSize/2)); err == nil ||
+		err.Error() != "the 4097x4097 board is larger than the maximum of 4096x4096" {
+		t.Fatalf("expected boards padded past the maximum size to be rejected, got %v", err)
+	}
+	config = strings.NewReader("#Life 1.06\n0 0\n")
+	if _, err := newLife(config, WithMargin(1<<40)); err == nil {
+		t.Fatal("expected a huge margin to be rejected")
+	}
+}
+
+func TestParseRule(t *testing.T) {
+	rules := map[string]string{
+		"B3/S23":       "B3/S23",
+		"b36/s23":      "B36/S23",
+		"S23/B36":      "B36/S23",
+		"23/3":         "B3/S23",
+		"34678/3678":   "B3678/S34678",
+		"B2/S":         "B2/S",
+		"B3S12345":     "B3/S12345",
+		"HighLife":     "B36/S23",
+		"Day & Night":  "B3678/S34678",
+		"seeds":        "B2/S",
+		"/2":           "B2/S",
+		"B012345678/S": "B012345678/S",
+	}
+	for notation, expected := range rules {
+		rule, err := ParseRule(notation)
+		if err != nil {
+			t.Fatalf("unexpected error parsing %s: %s", notation, err)
+		}
+		if rule.String() != expected {
+			t.Fatalf("expected %s to parse as %s, got %s", notation, expected, rule)
+		}
+	}
+
+	for _, notation := range []string{"", "B9/S23", "B3", "X3/S23", "B3/B23"} {
+		if _, err := ParseRule(notation); err == nil {
+			t.Fatalf("expected an error parsing %q", notation)
+		}
+	}
+}
+
+func TestRuleFromConfig(t *testing.T) {
+	p := parse("3 3 B36/S23\n---\n***\n---\n", t)
+	if p.rule != "B36/S23" {
+		t.Fatalf("expected rule B36/S23, got %s", p.rule)
+	}
+}
+
+func TestSeedsGeneration(t *testing.T) {
+	seeds, _ := ParseRule("seeds")
+	l := newTestLife("3 4\n----\n-**-\n----\n", t, WithRule(seeds))
+	l.NextGeneration()
+	expected := newTestLife("3 4\n-**-\n----\n-**-\n", t)
+	if l.Printable() != expected.Printable() {
+		t.Fatalf("unexpected board:\n%s", l.Printable())
+	}
+}
+
+func TestTopologyWrap(t *testing.T) {
+	cases := []struct {
+		topology Topology
+		x, y     int
+		wx, wy   int
+		onBoard  bool
+	}{
+		{Bounded, -1, 0, 0, 0, false},
+		{Bounded, 2, 3, 2, 3, true},
+		{Torus, -1, 5, 3, 0, true},
+		{Torus, 4, -1, 0, 4, true},
+		{KleinBottle, -1, 1, 3, 1, true},
+		{KleinBottle, 1, 5, 2, 0, true},
+		{CrossSurface, -1, 1, 3, 3, true},
+		{CrossSurface, 1, -1, 2, 4, true},
+	}
+	for _, c := range cases {
+		x, y, onBoard := c.topology.wrap(c.x, c.y, 4, 5)
+		if onBoard != c.onBoard || (onBoard && (x != c.wx || y != c.wy)) {
+			t.Fatalf("%s: expected (%d, %d) to map to (%d, %d), got (%d, %d)",
+				c.topology, c.x, c.y, c.wx, c.wy, x, y)
+		}
+	}
+}
+
+func TestGliderOnTorus(t *testing.T) {
+	l := newTestLife("x = 3, y = 3\nbo$2bo$3o!", t, WithMargin(1), WithTopology(Torus))
+	start := l.Printable()
+	// a glider travels one cell diagonally every 4 generations
+	for i := 0; i < 4*l.dimX; i++ {
+		l.NextGeneration()
+	}
+	if l.Printable() != start {
+		t.Fatalf("expected the glider to return to its start, got:\n%s", l.Printable())
+	}
+}
+
+func TestSparseGliderTravels(t *testing.T) {
+	l := newTestLife("x = 3, y = 3\nbo$2bo$3o!", t, WithMargin(0), WithTopology(Unbounded))
+	start := l.Printable()
+	for i := 0; i < 400; i++ {
+		l.NextGeneration()
+	}
+	if l.Printable() != start {
+		t.Fatalf("expected the glider to keep its shape, got:\n%s", l.Printable())
+	}
+	if !l.currentState.Get(100, 101) || l.currentState.Get(0, 1) {
+		t.Fatal("expected the glider to travel 100 cells diagonally")
+	}
+}
+
+func TestEngineTopologyCompatibility(t *testing.T) {
+	config := "x = 3, y = 3\nbo$2bo$3o!"
+	l := newTestLife(config, t, WithEngine(SparseEngine))
+	if l.Topology() != Unbounded {
+		t.Fatalf("expected the sparse engine to default to an unbounded universe")
+	}
+	invalid := [][]Option{
+		{WithEngine(SparseEngine), WithTopology(Torus)},
+		{WithEngine(DenseEngine), WithTopology(Unbounded)},
+		{WithEngine(PackedEngine), WithTopology(KleinBottle)},
+		{WithEngine("quantum")},
+		{WithTopology(Unbounded), WithRule(Rule{birth: [9]bool{0: true}})},
+	}
+	for _, opts := range invalid {
+		if _, err := newLife(strings.NewReader(config), opts...); err == nil {
+			t.Fatal("expected an error for incompatible options")
+		}
+	}
+}
+
+func TestPrintableRegion(t *testing.T) {
+	l := newTestLife("2 2\n*-\n-*\n", t, WithTopology(Torus))
+	expected := " *     * \n    *    \n"
+	if region := l.PrintableRegion(0, 0, 2, 3); region != expected {
+		t.Fatalf("expected region %q, got %q", expected, region)
+	}
+}
+
+func TestHashLifeMatchesSparse(t *testing.T) {
+	config := "#Life 1.06\n0 -1\n1 -1\n-1 0\n0 0\n0 1\n"
+	sparse := newTestLife(config, t, WithEngine(SparseEngine))
+	hashLife := newTestLife(config, t, WithEngine(HashLifeEngine))
+	for i := 0; i < 300; i++ {
+		sparse.NextGeneration()
+		hashLife.NextGeneration()
+		if sparse.Printable() != hashLife.Printable() {
+			t.Fatalf("engines diverged at generation %d", i+1)
+		}
+	}
+}
+
+func TestHashLifeJump(t *testing.T) {
+	config := "#Life 1.06\n0 -1\n1 -1\n-1 0\n0 0\n0 1\n"
+	sparse := newTestLife(config, t, WithEngine(SparseEngine))
+	for i := 0; i < 1024; i++ {
+		sparse.NextGeneration()
+	}
+	hashLife := newTestLife(config, t, WithEngine(HashLifeEngine), WithJump(10))
+	if hashLife.GenerationsPerStep() != 1024 {
+		t.Fatalf("expected 1024 generations per step, got %d", hashLife.GenerationsPerStep())
+	}
+	hashLife.NextGeneration()
+	if sparse.Printable() != hashLife.Printable() {
+		t.Fatal("expected a jump of 1024 generations to match stepping 1024 times")
+	}
+
+	if _, err := newLife(strings.NewReader(config), WithJump(3)); err == nil {
+		t.Fatal("expected an error jumping with the dense engine")
+	}
+}
+
+func newRandomLife(rows, cols int, engine string, opts ...Option) *Life {
+	random := rand.New(rand.NewSource(42))
+	p := &pattern{rows: rows, cols: cols, hasBoard: true}
+	for x := 0; x < rows; x++ {
+		for y := 0; y < cols; y++ {
+			if random.Intn(3) == 0 {
+				p.cells = append(p.cells, Cell{x, y})
+			}
+		}
+	}
+	o := options{engine: engine}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	o.resolve()
+	l, _ := newLifeFromPattern(p, o)
+	return l
+}
+
+func TestPackedMatchesDense(t *testing.T) {
+	highLife, _ := ParseRule("highlife")
+	for _, cols := range []int{5, 64, 130} {
+		for _, topology := range []Topology{Bounded, Torus} {
+			opts := []Option{WithTopology(topology), WithRule(highLife)}
+			dense := newRandomLife(70, cols, DenseEngine, opts...)
+			packed := newRandomLife(70, cols, PackedEngine, opts...)
+			for i := 0; i < 50; i++ {
+				dense.NextGeneration()
+				packed.NextGeneration()
+				if dense.Printable() != packed.Printable() {
+					t.Fatalf("%s board with %d columns diverged at generation %d",
+						topology, cols, i+1)
+				}
+			}
+		}
+	}
+}
+
+func benchmarkNextGeneration(engine string, b *testing.B) {
+	const size = 4096
+	l := newRandomLife(size, size, engine)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.NextGeneration()
+	}
+	b.ReportMetric(float64(size*size*b.N)/b.Elapsed().Seconds(), "cells/s")
+}
+
+func BenchmarkDenseNextGeneration4096(b *testing.B) {
+	benchmarkNextGeneration(DenseEngine, b)
+}
+
+func BenchmarkPackedNextGeneration4096(b *testing.B) {
+	benchmarkNextGeneration(PackedEngine, b)
+}
+
+func TestEnginesAgree(t *testing.T) {
+	config := EngineConfig{Rows: 8, Cols: 8, Rule: Conway}
+	blinker := Snapshot{{3, 2}, {3, 3}, {3, 4}}
+	for _, name := range Engines() {
+		config.Topology = engines[name].Topologies[0]
+		engine, err := NewEngine(name, config)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", name, err)
+		}
+		engine.Restore(blinker)
+		if engine.Population() != 3 || !engine.Get(3, 2) || engine.Get(2, 3) {
+			t.Fatalf("%s: expected the blinker to be restored", name)
+		}
+		clone := engine.Clone()
+		engine.StepN(3)
+		expected := Snapshot{{2, 3}, {3, 3}, {4, 3}}
+		if !slices.Equal(engine.Snapshot(), expected) {
+			t.Fatalf("%s: expected %v, got %v", name, expected, engine.Snapshot())
+		}
+		if !slices.Equal(clone.Snapshot(), blinker) {
+			t.Fatalf("%s: expected the clone to be independent", name)
+		}
+		engine.Set(2, 3, false)
+		if engine.Population() != 2 {
+			t.Fatalf("%s: expected a population of 2, got %d", name, engine.Population())
+		}
+	}
+
+	if _, err := NewEngine("quantum", config); err == nil {
+		t.Fatal("expected an error for an unknown engine")
+	}
+}
+
+func TestDetection(t *testing.T) {
+	cases := []struct {
+		config   string
+		opts     []Option
+		expected Status
+	}{
+		{"3 3\n*--\n---\n--*\n", nil, Status{Extinct, 1, 1, Cell{}}},
+		{"4 4\n----\n-**-\n-**-\n----\n", nil, Status{StillLife, 1, 0, Cell{}}},
+		{"5 5\n-----\n--*--\n--*--\n--*--\n-----\n", nil, Status{Oscillator, 2, 0, Cell{}}},
+		{"x = 3, y = 3\nbo$2bo$3o!", []Option{WithTopology(Unbounded)},
+			Status{Spaceship, 4, 0, Cell{1, 1}}},
+		{"x = 3, y = 2\n3o$bo!", []Option{WithTopology(Unbounded)},
+			Status{Oscillator, 2, 9, Cell{}}},
+		{"4 4\n----\n-**-\n-**-\n----\n", []Option{WithEngine(HashLifeEngine), WithJump(10)},
+			Status{StillLife, 1, 0, Cell{}}},
+		{"x = 3, y = 1\n3o!", []Option{WithEngine(HashLifeEngine), WithJump(1)},
+			Status{Oscillator, 2, 0, Cell{}}},
+		{"x = 3, y = 1\n3o!", []Option{WithEngine(HashLifeEngine), WithJump(10)},
+			Status{Oscillator, 2, 0, Cell{}}},
+	}
+	for _, c := range cases {
+		l := newTestLife(c.config, t, c.opts...)
+		for i := 0; i < 1200 && !l.Status().Settled(); i++ {
+			l.NextGeneration()
+		}
+		if l.Status() != c.expected {
+			t.Fatalf("expected %s, got %s", c.expected, l.Status())
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	blinker := "x = 3, y = 1\n3o!"
+	for _, engine := range Engines() {
+		l := newTestLife(blinker, t, WithEngine(engine), WithMargin(2))
+		l.NextGeneration()
+		stats := l.Stats()
+		if stats.Generation != 1 || stats.Population != 3 ||
+			stats.Births != 2 || stats.Deaths != 2 {
+			t.Fatalf("%s: unexpected stats %s", engine, stats)
+		}
+		if l.Topology() != Unbounded &&
+			(stats.TopLeft != Cell{1, 3} || stats.BottomRight != Cell{3, 3}) {
+			t.Fatalf("%s: unexpected bounding box %s", engine, stats)
+		}
+	}
+
+	l := newRandomLife(40, 40, DenseEngine)
+	for i := 0; i < PopulationHistory+10; i++ {
+		before := l.currentState.Population()
+		l.NextGeneration()
+		stats := l.Stats()
+		if before+stats.Births-stats.Deaths != stats.Population {
+			t.Fatalf("births and deaths do not add up at generation %d", i+1)
+		}
+	}
+	history := l.Stats().History
+	if len(history) != PopulationHistory || history[len(history)-1] != l.Stats().Population {
+		t.Fatalf("unexpected population history %v", history)
+	}
+}
+
+func TestDiffSnapshots(t *testing.T) {
+	before := Snapshot{{0, 0}, {0, 2}, {1, 1}}
+	after := Snapshot{{0, 1}, {0, 2}, {2, 0}, {2, 1}}
+	if births, deaths := diffSnapshots(before, after); births != 3 || deaths != 2 {
+		t.Fatalf("expected 3 births and 2 deaths, got %d and %d", births, deaths)
+	}
+}
+
+func TestReset(t *testing.T) {
+	l := newTestLife("x = 3, y = 3\nbo$2bo$3o!", t, WithTopology(Unbounded))
+	start := l.Printable()
+	for i := 0; i < 20; i++ {
+		l.NextGeneration()
+	}
+	l.Reset()
+	if l.Printable() != start || l.Generation() != 0 {
+		t.Fatalf("expected the game to be back at its start, got generation %d:\n%s",
+			l.Generation(), l.Printable())
+	}
+	if stats := l.Stats(); stats.Births != 0 || len(stats.History) != 1 {
+		t.Fatalf("expected the statistics to be reset, got %s", stats)
+	}
+	if l.Status().Settled() {
+		t.Fatal("expected the status to be reset")
+	}
+}
+
+func TestHistory(t *testing.T) {
+	reference := newRandomLife(30, 30, DenseEngine)
+	var states []string
+	var stats []Stats
+	for i := 0; i <= 200; i++ {
+		states = append(states, reference.Printable())
+		stats = append(stats, reference.Stats())
+		reference.NextGeneration()
+	}
+
+	l := newRandomLife(30, 30, DenseEngine, WithHistory(100))
+	for i := 0; i < 150; i++ {
+		l.NextGeneration()
+	}
+	if oldest, newest, _ := l.HistoryRange(); oldest != 50 || newest != 150 {
+		t.Fatalf("expected the history to span generations 50 to 150, got %d to %d", oldest, newest)
+	}
+	check := func(generation uint64) {
+		if l.Generation() != generation || l.Printable() != states[generation] {
+			t.Fatalf("expected the state of generation %d, got generation %d", generation, l.Generation())
+		}
+		actual, expected := l.Stats(), stats[generation]
+		if actual.Population != expected.Population || actual.Births != expected.Births ||
+			actual.Deaths != expected.Deaths {
+			t.Fatalf("expected stats %s, got %s", expected, actual)
+		}
+	}
+
+	if err := l.Rewind(40); err != nil {
+		t.Fatal(err)
+	}
+	check(110)
+	for _, generation := range []uint64{60, 50, 83, 150, 180} {
+		if err := l.Seek(generation); err != nil {
+			t.Fatal(err)
+		}
+		check(generation)
+	}
+	l.Seek(100)
+	l.NextGeneration()
+	check(101)
+	if _, newest, _ := l.HistoryRange(); newest != 101 {
+		t.Fatalf("expected advancing to discard the states past generation 101, got %d", newest)
+	}
+	l.Seek(90)
+	if steps, err := l.SeekHistory(105); err != nil || steps != 4 {
+		t.Fatalf("expected 4 steps to compute past the newest state, got %d, %v", steps, err)
+	}
+	check(101)
+
+	if err := l.Seek(40); err == nil {
+		t.Fatal("expected an error seeking past the history")
+	}
+	if err := l.Seek(101 + MaxSteps + 1); err == nil || l.Generation() != 101 {
+		t.Fatal("expected an error seeking too far past the history")
+	}
+	if err := l.Rewind(100); err == nil {
+		t.Fatal("expected an error rewinding past the history")
+	}
+	if err := newRandomLife(5, 5, DenseEngine).Rewind(1); err == nil {
+		t.Fatal("expected an error rewinding a game without history")
+	}
+}
+
+func TestClone(t *testing.T) {
+	l := newRandomLife(20, 20, DenseEngine, WithHistory(10))
+	for i := 0; i < 5; i++ {
+		l.NextGeneration()
+	}
+	clone := l.Clone()
+	for i := 0; i < 5; i++ {
+		clone.NextGeneration()
+	}
+	if l.Generation() != 5 || len(l.Stats().History) != 6 {
+		t.Fatal("expected the original game to be unaffected by its clone")
+	}
+	l.NextGeneration()
+	clone.Rewind(4)
+	if clone.Generation() != 6 || clone.Printable() != l.Printable() {
+		t.Fatal("expected the clone to carry over the history of the game")
+	}
+	clone.Reset()
+	l.Reset()
+	if clone.Printable() != l.Printable() {
+		t.Fatal("expected the clone to carry over the starting configuration")
+	}
+}
+
+func TestEditCells(t *testing.T) {
+	l := newTestLife("3 3\n---\n---\n---\n", t, WithHistory(4))
+	l.NextGeneration()
+	if err := l.SetCell(1, 0, true); err != nil {
+		t.Fatal(err)
+	}
+	l.ToggleCell(1, 1)
+	l.ToggleCell(1, 2)
+	if l.Population() != 3 || l.Stats().History[1] != 3 {
+		t.Fatalf("expected a blinker to be drawn, got:\n%s", l.Printable())
+	}
+	blinker := l.Printable()
+	l.NextGeneration()
+	l.Rewind(1)
+	if l.Printable() != blinker {
+		t.Fatal("expected the edited state to replace the state in the history")
+	}
+	l.Rewind(1)
+	if l.Population() != 0 {
+		t.Fatal("expected the states before the edit to be unaffected")
+	}
+	if err := l.SetCell(3, 0, true); err == nil {
+		t.Fatal("expected an error setting a cell outside the board")
+	}
+
+	l.FillRandom(1, 7)
+	if l.Population() != 9 {
+		t.Fatalf("expected a density of 1 to fill the board, got:\n%s", l.Printable())
+	}
+	l.Clear()
+	if l.Population() != 0 || l.Status().Behaviour != Extinct {
+		t.Fatal("expected the board to be cleared")
+	}
+	if err := l.FillRandom(1.5, 7); err == nil {
+		t.Fatal("expected an error for an invalid density")
+	}
+}
+
+func TestPlace(t *testing.T) {
+	glider := func(quarterTurns int, flip Flip) *pattern {
+		p := parse("x = 3, y = 3\nbo$2bo$3o!", t)
+		p.rotate(quarterTurns)
+		p.flip(flip)
+		newSnapshot(p.cells)
+		return p
+	}
+	assertCells(glider(1, NoFlip), []Cell{{0, 0}, {1, 0}, {1, 2}, {2, 0}, {2, 1}}, t)
+	assertCells(glider(0, FlipHorizontal), []Cell{{0, 1}, {1, 0}, {2, 0}, {2, 1}, {2, 2}}, t)
+	assertCells(glider(2, FlipVertical), []Cell{{0, 1}, {1, 0}, {2, 0}, {2, 1}, {2, 2}}, t)
+
+	l := newTestLife("6 6\n------\n------\n------\n------\n------\n------\n", t)
+	if err := l.stamp(glider(0, NoFlip), 3, 3); err != nil {
+		t.Fatal(err)
+	}
+	if l.Population() != 5 || !l.currentState.Get(5, 5) {
+		t.Fatalf("expected the glider in the bottom right corner, got:\n%s", l.Printable())
+	}
+	if err := l.stamp(glider(0, NoFlip), 4, 0); err == nil {
+		t.Fatal("expected an error placing a pattern off a bounded board")
+	}
+
+	torus := newTestLife("2 2\n--\n--\n", t, WithTopology(Torus))
+	if err := torus.stamp(glider(0, NoFlip), 0, 0); err == nil {
+		t.Fatal("expected an error placing a pattern larger than the board")
+	}
+
+	for _, rotation := range []string{"0", "90", "-90", "540"} {
+		if _, err := ParseRotation(rotation); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := ParseRotation("45"); err == nil {
+		t.Fatal("expected an error for a rotation which is not a multiple of 90")
+	}
+}
+
+func TestTransforms(t *testing.T) {
+	config := "x = 3, y = 2\n3o$o!"
+	l := newTestLife(config, t, WithMargin(1), WithTransforms(Rotate(1), Pad(1)))
+	if l.dimX != 7 || l.dimY != 6 {
+		t.Fatalf("expected a 7x6 board, got %dx%d", l.dimX, l.dimY)
+	}
+	expected := Snapshot{{2, 2}, {2, 3}, {3, 3}, {4, 3}}
+	if !slices.Equal(l.currentState.Snapshot(), expected) {
+		t.Fatalf("expected %v, got %v", expected, l.currentState.Snapshot())
+	}
+
+	l.NextGeneration()
+	if err := l.Transform(Crop(), Transpose(), Resize(2, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if l.dimX != 2 || l.dimY != 2 || l.Generation() != 1 {
+		t.Fatalf("expected a 2x2 board at generation 1, got %dx%d at %d",
+			l.dimX, l.dimY, l.Generation())
+	}
+	if err := l.Transform(Pad(-1)); err == nil || l.dimX != 2 {
+		t.Fatal("expected a failing transform to leave the game unchanged")
+	}
+	for _, transform := range []Transform{Pad(1 << 62), Pad(MaxBoardSize / 2), Resize(2, MaxBoardSize+1)} {
+		if err := l.Transform(transform); err == nil || l.dimX != 2 {
+			t.Fatal("expected transforms past the maximum board size to be rejected")
+		}
+	}
+	l.Reset()
+	if l.dimX != 7 || !slices.Equal(l.currentState.Snapshot(), expected) {
+		t.Fatal("expected resetting to restore the transformed starting board")
+	}
+
+	torus := newTestLife("2 3\n*--\n---\n", t, WithTopology(Torus))
+	torus.Transform(Translate(-1, 4))
+	if !torus.currentState.Get(1, 1) || torus.Population() != 1 {
+		t.Fatalf("expected the cell to wrap around, got:\n%s", torus.Printable())
+	}
+	bounded := newTestLife("2 3\n*--\n---\n", t)
+	bounded.Transform(Translate(2, 0))
+	if bounded.Population() != 0 {
+		t.Fatal("expected the cell to be dropped past the edge of the board")
+	}
+
+	for _, transform := range []string{"rotate:270", "flip:v", "transpose", "crop",
+		"pad:3", "resize:4x5", "translate:-1,2"} {
+		if _, err := ParseTransform(transform); err != nil {
+			t.Fatalf("unexpected error for %s: %s", transform, err)
+		}
+	}
+	for _, transform := range []string{"rotate", "crop:1", "resize:4", "translate:a,1", "skew:2"} {
+		if _, err := ParseTransform(transform); err == nil {
+			t.Fatalf("expected an error for %s", transform)
+		}
+	}
+}
+
+func TestSoup(t *testing.T) {
+	first, err := NewLife("random:20x10:0.4:42", WithMargin(0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _ := NewLife("random:20x10:0.4:42", WithMargin(0))
+	other, _ := NewLife("random:20x10:0.4:43", WithMargin(0))
+	if first.dimX != 10 || first.dimY != 20 {
+		t.Fatalf("expected a board of 10 rows and 20 columns, got %dx%d", first.dimX, first.dimY)
+	}
+	if first.Printable() != second.Printable() || first.Printable() == other.Printable() {
+		t.Fatal("expected soups to be determined by their seed")
+	}
+
+	for symmetry, size := range map[string]int{"C2": 9, "C4": 10, "D8": 11} {
+		config := fmt.Sprintf("random:%dx%d:0.5:7:%s", size, size, symmetry)
+		p, err := parseSoup(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alive := make(map[Cell]bool)
+		for _, c := range p.cells {
+			alive[c] = true
+		}
+		images := soupSymmetries[symmetry]
+		for _, c := range p.cells {
+			for _, image := range images(c, size, size) {
+				if !alive[image] {
+					t.Fatalf("%s: expected %v to be alive as the image of %v", symmetry, image, c)
+				}
+			}
+		}
+	}
+
+	for _, config := range []string{"random:20x10:0.4", "random:20:0.4:1", "random:0x10:0.4:1",
+		"random:20x10:2:1", "random:20x10:0.4:1:C3", "random:20x10:0.4:1:D8"} {
+		if _, err := NewLife(config); err == nil {
+			t.Fatalf("expected an error for %s", config)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	highLife, _ := ParseRule("highlife")
+	for _, engine := range []string{DenseEngine, PackedEngine, SparseEngine} {
+		topology := Torus
+		if engine == SparseEngine {
+			topology = Unbounded
+		}
+		l := newRandomLife(20, 20, engine, WithTopology(topology),
+			WithRule(highLife), WithHistory(3))
+		for i := 0; i < 7; i++ {
+			l.NextGeneration()
+		}
+		restored, err := Restore(l.Record())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if restored.Generation() != 7 || restored.Printable() != l.Printable() ||
+			restored.Rule() != l.Rule() || restored.Topology() != topology {
+			t.Fatalf("expected the %s game to be restored as it was", engine)
+		}
+		l.NextGeneration()
+		restored.NextGeneration()
+		if restored.Printable() != l.Printable() {
+			t.Fatalf("expected the restored %s game to evolve like the original", engine)
+		}
+		if err := restored.Rewind(1); err != nil {
+			t.Fatal("expected the restored game to keep its history depth")
+		}
+		restored.Reset()
+		l.Reset()
+		if restored.Printable() != l.Printable() || restored.Generation() != 0 {
+			t.Fatalf("expected the restored %s game to keep its starting board", engine)
+		}
+	}
+
+	record := newTestLife("2 2\n--\n--\n", t).Record()
+	record.Current.Cells = []Cell{{2, 0}}
+	if _, err := Restore(record); err == nil || err.Error() != "cell (2, 0) is outside the board" {
+		t.Fatalf("expected cells outside the board to be rejected, got %v", err)
+	}
+	record.Rule = "B9"
+	if _, err := Restore(record); err == nil {
+		t.Fatal("expected an invalid rule to be rejected")
+	}
+}
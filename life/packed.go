@@ -0,0 +1,249 @@
+package life
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/bits"
+	"runtime"
+	"sync"
+)
+
+// Boards with fewer words than this are stepped on a single goroutine as
+// splitting them costs more than it saves.
+const minParallelWords = 1 << 12
+
+// A packed engine is a fixed size board storing 64 cells per word. Bit `i`
+// of word `w` of a row is the cell in column 64*w+i. The bits past the last
+// column are always zero.
+// Neighbours are counted for 64 cells at a time by adding the shifted rows
+// bit by bit, and the board is split into row bands stepped concurrently.
+// Only the Bounded and Torus topologies are supported.
+type packedEngine struct {
+	currentState []uint64
+	tempState    []uint64
+	dimX, dimY   int
+	words        int
+	lastMask     uint64
+	topology     Topology
+	rule         Rule
+	births       int
+	deaths       int
+}
+
+func newPackedEngine(config EngineConfig) Engine {
+	return newPackedBoard(config.Rows, config.Cols, config.Topology, config.Rule)
+}
+
+func newPackedBoard(dimX, dimY int, topology Topology, rule Rule) *packedEngine {
+	words := (dimY + 63) / 64
+	e := &packedEngine{
+		currentState: make([]uint64, dimX*words),
+		tempState:    make([]uint64, dimX*words),
+		dimX:         dimX,
+		dimY:         dimY,
+		words:        words,
+		lastMask:     ^uint64(0),
+		topology:     topology,
+		rule:         rule,
+	}
+	if dimY%64 != 0 {
+		e.lastMask = 1<<(dimY%64) - 1
+	}
+	return e
+}
+
+func (e *packedEngine) Get(x, y int) bool {
+	x, y, onBoard := e.topology.wrap(x, y, e.dimX, e.dimY)
+	return onBoard && e.currentState[x*e.words+y/64]&(1<<(y%64)) != 0
+}
+
+func (e *packedEngine) Set(x, y int, isAlive bool) bool {
+	x, y, onBoard := e.topology.wrap(x, y, e.dimX, e.dimY)
+	if !onBoard {
+		return false
+	}
+	if isAlive {
+		e.currentState[x*e.words+y/64] |= 1 << (y % 64)
+	} else {
+		e.currentState[x*e.words+y/64] &^= 1 << (y % 64)
+	}
+	return true
+}
+
+// Returns the row `x` of the current state, nil if it is outside a bounded
+// board.
+func (e *packedEngine) row(x int) []uint64 {
+	if x < 0 || x >= e.dimX {
+		if e.topology == Bounded {
+			return nil
+		}
+		x = mod(x, e.dimX)
+	}
+	return e.currentState[x*e.words : (x+1)*e.words]
+}
+
+// Returns the word `w` of `row` shifted so that every bit holds its west
+// (column-1) and east (column+1) neighbour.
+func (e *packedEngine) shifted(row []uint64, w int) (uint64, uint64, uint64) {
+	if row == nil {
+		return 0, 0, 0
+	}
+	word := row[w]
+	west, east := word<<1, word>>1
+	if w > 0 {
+		west |= row[w-1] >> 63
+	} else if e.topology == Torus {
+		west |= row[e.words-1] >> ((e.dimY - 1) % 64) & 1
+	}
+	if w < e.words-1 {
+		east |= row[w+1] << 63
+	} else if e.topology == Torus {
+		east |= (row[0] & 1) << ((e.dimY - 1) % 64)
+	}
+	return west, word, east
+}
+
+func halfAdd(a, b uint64) (uint64, uint64) {
+	return a ^ b, a & b
+}
+
+func fullAdd(a, b, c uint64) (uint64, uint64) {
+	s := a ^ b
+	return s ^ c, a&b | s&c
+}
+
+// Computes the next state of the words of rows [from, to) and returns the
+// number of births and deaths among them.
+func (e *packedEngine) stepRows(from, to int) (int, int) {
+	births, deaths := 0, 0
+	for x := from; x < to; x++ {
+		above, current, below := e.row(x-1), e.row(x), e.row(x+1)
+		for w := 0; w < e.words; w++ {
+			nw, n, ne := e.shifted(above, w)
+			west, self, east := e.shifted(current, w)
+			sw, s, se := e.shifted(below, w)
+
+			// add up the eight neighbours into the bits of the count
+			s0, c0 := fullAdd(nw, n, ne)
+			s1, c1 := fullAdd(sw, s, se)
+			s2, c2 := halfAdd(west, east)
+			bit0, c3 := fullAdd(s0, s1, s2)
+			t, c4 := fullAdd(c0, c1, c2)
+			bit1, c5 := halfAdd(t, c3)
+			bit2, bit3 := halfAdd(c4, c5)
+
+			var next uint64
+			for count := 0; count <= 8; count++ {
+				if !e.rule.birth[count] && !e.rule.survival[count] {
+					continue
+				}
+				equal := ^uint64(0)
+				for bit, plane := range [4]uint64{bit0, bit1, bit2, bit3} {
+					if count&(1<<bit) != 0 {
+						equal &= plane
+					} else {
+						equal &^= plane
+					}
+				}
+				if e.rule.birth[count] {
+					next |= equal &^ self
+				}
+				if e.rule.survival[count] {
+					next |= equal & self
+				}
+			}
+			if w == e.words-1 {
+				next &= e.lastMask
+			}
+			e.tempState[x*e.words+w] = next
+			births += bits.OnesCount64(next &^ self)
+			deaths += bits.OnesCount64(self &^ next)
+		}
+	}
+	return births, deaths
+}
+
+func (e *packedEngine) Step() {
+	bands := runtime.GOMAXPROCS(0)
+	if bands > e.dimX || len(e.currentState) < minParallelWords {
+		bands = 1
+	}
+	births := make([]int, bands)
+	deaths := make([]int, bands)
+	var wg sync.WaitGroup
+	for band := 0; band < bands; band++ {
+		from, to := band*e.dimX/bands, (band+1)*e.dimX/bands
+		wg.Add(1)
+		go func(band int) {
+			defer wg.Done()
+			births[band], deaths[band] = e.stepRows(from, to)
+		}(band)
+	}
+	wg.Wait()
+	e.currentState, e.tempState = e.tempState, e.currentState
+	e.births, e.deaths = 0, 0
+	for band := range births {
+		e.births += births[band]
+		e.deaths += deaths[band]
+	}
+}
+
+func (e *packedEngine) StepN(n int) {
+	for i := 0; i < n; i++ {
+		e.Step()
+	}
+}
+
+func (e *packedEngine) changes() (int, int) {
+	return e.births, e.deaths
+}
+
+func (e *packedEngine) Bounds() (int, int) {
+	return e.dimX, e.dimY
+}
+
+func (e *packedEngine) Population() int {
+	population := 0
+	for _, word := range e.currentState {
+		population += bits.OnesCount64(word)
+	}
+	return population
+}
+
+func (e *packedEngine) Snapshot() Snapshot {
+	var cells []Cell
+	for x := 0; x < e.dimX; x++ {
+		for w, word := range e.currentState[x*e.words : (x+1)*e.words] {
+			for bit := 0; word != 0; bit++ {
+				if word&1 != 0 {
+					cells = append(cells, Cell{x, 64*w + bit})
+				}
+				word >>= 1
+			}
+		}
+	}
+	return Snapshot(cells)
+}
+
+func (e *packedEngine) Restore(snapshot Snapshot) {
+	clear(e.currentState)
+	for _, c := range snapshot {
+		e.Set(c.X, c.Y, true)
+	}
+}
+
+func (e *packedEngine) Clone() Engine {
+	c := newPackedBoard(e.dimX, e.dimY, e.topology, e.rule)
+	copy(c.currentState, e.currentState)
+	return c
+}
+
+func (e *packedEngine) stateHash() uint64 {
+	hash := fnv.New64a()
+	var buffer [8]byte
+	for _, word := range e.currentState {
+		binary.LittleEndian.PutUint64(buffer[:], word)
+		hash.Write(buffer[:])
+	}
+	return hash.Sum64()
+}
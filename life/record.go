@@ -0,0 +1,121 @@
+package life
+
+import (
+	"errors"
+	"fmt"
+)
+
+// A Record is the persistent form of a game: how it evolves along with the
+// board it started from and its current board. Statistics, status and history
+// are not recorded, a game restored from a record begins them anew at its
+// current board.
+type Record struct {
+	Rule       string
+	Topology   string
+	Engine     string
+	Jump       uint `json:",omitempty"`
+	History    int  `json:",omitempty"`
+	Generation uint64
+	Start      Board
+	Current    Board
+}
+
+// A Board lists the live cells of a board with `Rows` rows and `Cols`
+// columns.
+type Board struct {
+	Rows, Cols int
+	Cells      []Cell
+}
+
+// Returns the persistent form of the game.
+func (l *Life) Record() Record {
+	return Record{
+		Rule:       l.rule.String(),
+		Topology:   l.topology.String(),
+		Engine:     l.engine,
+		Jump:       l.jump,
+		History:    l.historyDepth,
+		Generation: l.generation,
+		Start:      Board{l.startDimX, l.startDimY, l.startConfig.Snapshot()},
+		Current:    Board{l.dimX, l.dimY, l.currentState.Snapshot()},
+	}
+}
+
+// Constructs the game described by `record`. Resetting the game restores the
+// board it started from.
+// Fails if the record is invalid, e.g. if any of its cells is outside its
+// board.
+func Restore(record Record) (*Life, error) {
+	rule, err := ParseRule(record.Rule)
+	if err != nil {
+		return nil, err
+	}
+	topology, err := ParseTopology(record.Topology)
+	if err != nil {
+		return nil, err
+	}
+	if record.History < 0 {
+		return nil, errors.New("the history depth must not be negative")
+	}
+	o := options{
+		rule:     &rule,
+		topology: &topology,
+		engine:   record.Engine,
+		jump:     record.Jump,
+		history:  record.History,
+	}
+	if err := o.resolve(); err != nil {
+		return nil, err
+	}
+	start, err := newBoardEngine(record.Start, o)
+	if err != nil {
+		return nil, err
+	}
+	current, err := newBoardEngine(record.Current, o)
+	if err != nil {
+		return nil, err
+	}
+
+	l := &Life{
+		startConfig:  start,
+		currentState: current,
+		engine:       o.engine,
+		dimX:         record.Current.Rows,
+		dimY:         record.Current.Cols,
+		startDimX:    record.Start.Rows,
+		startDimY:    record.Start.Cols,
+		rule:         rule,
+		topology:     topology,
+		jump:         o.jump,
+		generation:   record.Generation,
+		historyDepth: o.history,
+	}
+	l.stats.record(l.currentState.Population())
+	l.detector = newDetector()
+	l.observe()
+	l.restartHistory()
+	return l, nil
+}
+
+// Constructs an engine for the resolved options `o` holding `board`.
+func newBoardEngine(board Board, o options) (Engine, error) {
+	if board.Rows <= 0 || board.Cols <= 0 {
+		return nil, errors.New("the board must have positive dimensions")
+	}
+	engine, err := NewEngine(o.engine, EngineConfig{
+		Rows:     board.Rows,
+		Cols:     board.Cols,
+		Topology: *o.topology,
+		Rule:     *o.rule,
+		Jump:     o.jump,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range board.Cells {
+		if !engine.Set(c.X, c.Y, true) {
+			return nil, fmt.Errorf("cell (%d, %d) is outside the board", c.X, c.Y)
+		}
+	}
+	return engine, nil
+}
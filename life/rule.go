@@ -0,0 +1,106 @@
+package life
+
+import (
+	"errors"
+	"strings"
+)
+
+// A Rule is an outer-totalistic rule described by the numbers of live
+// neighbours for which a dead cell is born and a live cell survives.
+type Rule struct {
+	birth, survival [9]bool
+}
+
+// Conway's Game of Life, B3/S23.
+var Conway = Rule{
+	birth:    [9]bool{3: true},
+	survival: [9]bool{2: true, 3: true},
+}
+
+// Well known rules which can be referred to by name instead of by notation.
+var namedRules = map[string]string{
+	"LIFE":             "B3/S23",
+	"HIGHLIFE":         "B36/S23",
+	"DAYNIGHT":         "B3678/S34678",
+	"SEEDS":            "B2/S",
+	"MAZE":             "B3/S12345",
+	"REPLICATOR":       "B1357/S1357",
+	"2X2":              "B36/S125",
+	"LIFEWITHOUTDEATH": "B3/S012345678",
+}
+
+func invalidRule(rule string) error {
+	return errors.New("invalid rule " + rule)
+}
+
+// Parses a rule in B/S notation (e.g. `B36/S23`), in S/B notation (e.g.
+// `23/3`) or given by name (e.g. `highlife`).
+func ParseRule(rule string) (Rule, error) {
+	notation := strings.ToUpper(strings.TrimSpace(rule))
+	if named, found := namedRules[strings.NewReplacer(" ", "", "&", "", "-", "").Replace(notation)]; found {
+		notation = named
+	}
+
+	var first, second string
+	if before, after, found := strings.Cut(notation, "/"); found {
+		first, second = before, after
+	} else if idx := strings.Index(notation, "S"); strings.HasPrefix(notation, "B") && idx != -1 {
+		first, second = notation[:idx], notation[idx:]
+	} else {
+		return Rule{}, invalidRule(rule)
+	}
+
+	var r Rule
+	switch {
+	case strings.HasPrefix(first, "B") && strings.HasPrefix(second, "S"):
+		return r, r.parseCounts(first[1:], second[1:], rule)
+	case strings.HasPrefix(first, "S") && strings.HasPrefix(second, "B"):
+		return r, r.parseCounts(second[1:], first[1:], rule)
+	case !strings.ContainsAny(first+second, "BS"):
+		return r, r.parseCounts(second, first, rule)
+	}
+	return Rule{}, invalidRule(rule)
+}
+
+func (r *Rule) parseCounts(birth, survival, rule string) error {
+	for _, count := range birth {
+		if count < '0' || count > '8' {
+			return invalidRule(rule)
+		}
+		r.birth[count-'0'] = true
+	}
+	for _, count := range survival {
+		if count < '0' || count > '8' {
+			return invalidRule(rule)
+		}
+		r.survival[count-'0'] = true
+	}
+	return nil
+}
+
+// Returns the rule in B/S notation.
+func (r Rule) String() string {
+	var notation strings.Builder
+	notation.WriteString("B")
+	for count, born := range r.birth {
+		if born {
+			notation.WriteByte(byte('0' + count))
+		}
+	}
+	notation.WriteString("/S")
+	for count, survives := range r.survival {
+		if survives {
+			notation.WriteByte(byte('0' + count))
+		}
+	}
+	return notation.String()
+}
+
+// Reports whether a cell is alive in the next generation given whether it is
+// currently alive and the number of its live neighbours.
+func (r Rule) livesOn(isAlive bool, aliveNeighboursCnt uint8) bool {
+	if isAlive {
+		return r.survival[aliveNeighboursCnt]
+	}
+	return r.birth[aliveNeighboursCnt]
+}
@@ -0,0 +1,106 @@
+package life
+
+import (
+	"errors"
+	"math/rand"
+	"strconv"
+	"strings"
+)
+
+// Configurations starting with this prefix describe random soups.
+const soupPrefix = "random:"
+
+// The largest width and height of a soup.
+const MaxSoupSize = 4096
+
+// The symmetries a soup may be generated with, along with the functions
+// returning the images of a cell under each element of their group.
+var soupSymmetries = map[string]func(c Cell, rows, cols int) []Cell{
+	"C1": func(c Cell, _, _ int) []Cell {
+		return []Cell{c}
+	},
+	// symmetric under a half turn
+	"C2": func(c Cell, rows, cols int) []Cell {
+		return []Cell{c, {rows - 1 - c.X, cols - 1 - c.Y}}
+	},
+	// symmetric under a quarter turn
+	"C4": func(c Cell, rows, _ int) []Cell {
+		n := rows - 1
+		return []Cell{c, {c.Y, n - c.X}, {n - c.X, n - c.Y}, {n - c.Y, c.X}}
+	},
+	// symmetric under quarter turns and reflections
+	"D8": func(c Cell, rows, _ int) []Cell {
+		n := rows - 1
+		return []Cell{
+			c, {c.Y, n - c.X}, {n - c.X, n - c.Y}, {n - c.Y, c.X},
+			{c.X, n - c.Y}, {c.Y, c.X}, {n - c.X, c.Y}, {n - c.Y, n - c.X},
+		}
+	},
+}
+
+func invalidSoup(config string) error {
+	return errors.New("invalid soup " + config +
+		", expected random:<width>x<height>:<density>:<seed>[:C2|C4|D8]")
+}
+
+// Generates the soup described by `config`:
+// `random:<width>x<height>:<density>:<seed>[:<symmetry>]`. Every cell of the
+// width×height soup is alive with probability `density`, drawn from a source
+// seeded with `seed`, so equal configurations generate equal soups. The soup
+// may be made symmetric under a half turn (C2), a quarter turn (C4) or all the
+// symmetries of the square (D8); the latter two require square soups.
+func parseSoup(config string) (*pattern, error) {
+	fields := strings.Split(strings.TrimPrefix(config, soupPrefix), ":")
+	if len(fields) != 3 && len(fields) != 4 {
+		return nil, invalidSoup(config)
+	}
+	width, height, found := strings.Cut(fields[0], "x")
+	cols, errCols := strconv.Atoi(width)
+	rows, errRows := strconv.Atoi(height)
+	density, errDensity := strconv.ParseFloat(fields[1], 64)
+	seed, errSeed := strconv.ParseInt(fields[2], 10, 64)
+	if !found || errCols != nil || errRows != nil || errDensity != nil || errSeed != nil {
+		return nil, invalidSoup(config)
+	}
+	if rows <= 0 || cols <= 0 || rows > MaxSoupSize || cols > MaxSoupSize {
+		return nil, errors.New("the dimensions of a soup must be between 1 and " +
+			strconv.Itoa(MaxSoupSize))
+	}
+	if !(density >= 0 && density <= 1) {
+		return nil, errors.New("the density must be between 0 and 1")
+	}
+	symmetry := "C1"
+	if len(fields) == 4 {
+		symmetry = strings.ToUpper(fields[3])
+	}
+	images, found := soupSymmetries[symmetry]
+	if !found {
+		return nil, errors.New("unknown symmetry " + fields[3] + ", expected C2, C4 or D8")
+	}
+	if (symmetry == "C4" || symmetry == "D8") && rows != cols {
+		return nil, errors.New("the " + symmetry + " symmetry requires a square soup")
+	}
+
+	// cells are decided in order, each along with its images
+	random := rand.New(rand.NewSource(seed))
+	decided := make([]bool, rows*cols)
+	p := &pattern{rows: rows, cols: cols}
+	for x := 0; x < rows; x++ {
+		for y := 0; y < cols; y++ {
+			if decided[x*cols+y] {
+				continue
+			}
+			isAlive := random.Float64() < density
+			for _, c := range images(Cell{x, y}, rows, cols) {
+				if decided[c.X*cols+c.Y] {
+					continue
+				}
+				decided[c.X*cols+c.Y] = true
+				if isAlive {
+					p.cells = append(p.cells, c)
+				}
+			}
+		}
+	}
+	return p, nil
+}
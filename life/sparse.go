@@ -0,0 +1,108 @@
+package life
+
+// A sparse engine runs an unbounded universe: it only stores the coordinates
+// of the live cells, so patterns may grow and travel without ever reaching an
+// edge.
+type sparseEngine struct {
+	cells  map[Cell]struct{}
+	rule   Rule
+	births int
+	deaths int
+}
+
+func newSparseEngine(config EngineConfig) Engine {
+	return &sparseEngine{cells: make(map[Cell]struct{}), rule: config.Rule}
+}
+
+func (e *sparseEngine) Get(x, y int) bool {
+	_, found := e.cells[Cell{x, y}]
+	return found
+}
+
+func (e *sparseEngine) Set(x, y int, isAlive bool) bool {
+	if isAlive {
+		e.cells[Cell{x, y}] = struct{}{}
+	} else {
+		delete(e.cells, Cell{x, y})
+	}
+	return true
+}
+
+// Only the live cells and their neighbours can be alive in the next
+// generation, so those are the only ones considered. Rules with B0 are not
+// supported as they would bring the entire universe to life.
+func (e *sparseEngine) Step() {
+	neighbours := make(map[Cell]uint8, 8*len(e.cells))
+	for c := range e.cells {
+		for dx := -1; dx <= 1; dx++ {
+			for dy := -1; dy <= 1; dy++ {
+				if dx != 0 || dy != 0 {
+					neighbours[Cell{c.X + dx, c.Y + dy}]++
+				}
+			}
+		}
+	}
+	next := make(map[Cell]struct{}, len(e.cells))
+	for c, count := range neighbours {
+		if e.rule.livesOn(e.Get(c.X, c.Y), count) {
+			next[c] = struct{}{}
+		}
+	}
+	// live cells without live neighbours are not in `neighbours`
+	if e.rule.survival[0] {
+		for c := range e.cells {
+			if _, found := neighbours[c]; !found {
+				next[c] = struct{}{}
+			}
+		}
+	}
+	e.births, e.deaths = 0, 0
+	for c := range next {
+		if !e.Get(c.X, c.Y) {
+			e.births++
+		}
+	}
+	e.deaths = len(e.cells) + e.births - len(next)
+	e.cells = next
+}
+
+func (e *sparseEngine) StepN(n int) {
+	for i := 0; i < n; i++ {
+		e.Step()
+	}
+}
+
+func (e *sparseEngine) changes() (int, int) {
+	return e.births, e.deaths
+}
+
+func (e *sparseEngine) Bounds() (int, int) {
+	return 0, 0
+}
+
+func (e *sparseEngine) Population() int {
+	return len(e.cells)
+}
+
+func (e *sparseEngine) Snapshot() Snapshot {
+	cells := make([]Cell, 0, len(e.cells))
+	for c := range e.cells {
+		cells = append(cells, c)
+	}
+	return newSnapshot(cells)
+}
+
+func (e *sparseEngine) Restore(snapshot Snapshot) {
+	e.cells = make(map[Cell]struct{}, len(snapshot))
+	for _, c := range snapshot {
+		e.cells[c] = struct{}{}
+	}
+}
+
+func (e *sparseEngine) Clone() Engine {
+	cells := make(map[Cell]struct{}, len(e.cells))
+	for c := range e.cells {
+		cells[c] = struct{}{}
+	}
+	return &sparseEngine{cells: cells, rule: e.rule}
+}
@@ -0,0 +1,130 @@
+package life
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The number of most recent populations remembered by a game.
+const PopulationHistory = 64
+
+// The Stats of a game describe its current generation and how it got there.
+// Births and deaths are counted over the last step, which spans several
+// generations for games constructed with WithJump.
+type Stats struct {
+	Generation uint64
+	Population int
+	Births     int
+	Deaths     int
+	// The smallest rectangle containing all the live cells given by its top
+	// left and bottom right cells, zero when there are no live cells.
+	TopLeft, BottomRight Cell
+	// The populations of the most recent generations, oldest first.
+	History []int
+}
+
+// Returns the counters of the stats in a single line.
+func (s Stats) String() string {
+	bounds := "empty"
+	if s.Population > 0 {
+		bounds = fmt.Sprintf("(%d, %d)-(%d, %d)",
+			s.TopLeft.X, s.TopLeft.Y, s.BottomRight.X, s.BottomRight.Y)
+	}
+	return fmt.Sprintf("generation %d, population %d (+%d -%d), bounding box %s",
+		s.Generation, s.Population, s.Births, s.Deaths, bounds)
+}
+
+// Returns the counters of the stats followed by the population history.
+func (s Stats) Detailed() string {
+	history := make([]string, len(s.History))
+	for i, population := range s.History {
+		history[i] = strconv.Itoa(population)
+	}
+	return s.String() + "\npopulation history: " + strings.Join(history, " ")
+}
+
+// Engines counting the cells born and died during their last step.
+type changeCounter interface {
+	changes() (int, int)
+}
+
+// Keeps the counters of a game up to date as it advances.
+type statsTracker struct {
+	births, deaths int
+	history        []int
+	next           int
+}
+
+func (t *statsTracker) record(population int) {
+	if len(t.history) < PopulationHistory {
+		t.history = append(t.history, population)
+		return
+	}
+	t.history[t.next] = population
+	t.next = (t.next + 1) % PopulationHistory
+}
+
+// Replaces the most recently recorded population.
+func (t *statsTracker) amend(population int) {
+	t.history[(t.next+len(t.history)-1)%len(t.history)] = population
+}
+
+// Advances `engine` by a step and records the changes it made.
+func (t *statsTracker) step(engine Engine) {
+	if counter, ok := engine.(changeCounter); ok {
+		engine.Step()
+		t.births, t.deaths = counter.changes()
+	} else {
+		before := engine.Snapshot()
+		engine.Step()
+		t.births, t.deaths = diffSnapshots(before, engine.Snapshot())
+	}
+	t.record(engine.Population())
+}
+
+// Returns the population history, oldest first.
+func (t *statsTracker) populations() []int {
+	history := make([]int, 0, len(t.history))
+	history = append(history, t.history[t.next:]...)
+	return append(history, t.history[:t.next]...)
+}
+
+// Returns the number of cells alive in `after` but not in `before` and the
+// number of cells alive in `before` but not in `after`.
+func diffSnapshots(before, after Snapshot) (int, int) {
+	births, deaths := 0, 0
+	compareSnapshots(before, after, func(_ Cell, born bool) {
+		if born {
+			births++
+		} else {
+			deaths++
+		}
+	})
+	return births, deaths
+}
+
+// Calls `changed` for every cell alive in exactly one of `before` and `after`,
+// reporting whether it is alive in `after`.
+func compareSnapshots(before, after Snapshot, changed func(c Cell, born bool)) {
+	i, j := 0, 0
+	for i < len(before) && j < len(after) {
+		switch b, a := before[i], after[j]; {
+		case b == a:
+			i++
+			j++
+		case b.X < a.X || b.X == a.X && b.Y < a.Y:
+			changed(b, false)
+			i++
+		default:
+			changed(a, true)
+			j++
+		}
+	}
+	for ; i < len(before); i++ {
+		changed(before[i], false)
+	}
+	for ; j < len(after); j++ {
+		changed(after[j], true)
+	}
+}
@@ -0,0 +1,86 @@
+package life
+
+import (
+	"errors"
+	"strings"
+)
+
+// A Topology describes how the edges of the board are connected.
+type Topology uint8
+
+const (
+	// Everything outside the board is dead.
+	Bounded Topology = iota
+	// Opposite edges are connected.
+	Torus
+	// The top and bottom edges are connected, the left and right edges are
+	// connected with a twist.
+	KleinBottle
+	// Opposite edges are connected with a twist (the real projective plane).
+	CrossSurface
+	// There are no edges, the universe grows as needed.
+	Unbounded
+)
+
+var topologyNames = map[Topology]string{
+	Bounded:      "bounded",
+	Torus:        "torus",
+	KleinBottle:  "klein",
+	CrossSurface: "cross",
+	Unbounded:    "unbounded",
+}
+
+// Parses a topology given by name. Aside from the names returned by String,
+// `klein-bottle`, `cross-surface` and `projective-plane` are recognized.
+func ParseTopology(name string) (Topology, error) {
+	switch strings.ToLower(name) {
+	case "bounded":
+		return Bounded, nil
+	case "torus":
+		return Torus, nil
+	case "klein", "klein-bottle":
+		return KleinBottle, nil
+	case "cross", "cross-surface", "projective-plane":
+		return CrossSurface, nil
+	case "unbounded", "infinite":
+		return Unbounded, nil
+	}
+	return Bounded, errors.New("unknown topology " + name)
+}
+
+// Returns the name of the topology.
+func (t Topology) String() string {
+	return topologyNames[t]
+}
+
+func mod(a, b int) int {
+	return (a%b + b) % b
+}
+
+// Maps the cell at `x`, `y`, which may lie outside a `dimX`×`dimY` board, onto
+// the board. Reports false if the cell is outside a bounded board.
+// Unbounded universes have no board, so cells are never mapped.
+func (t Topology) wrap(x, y, dimX, dimY int) (int, int, bool) {
+	if t == Unbounded {
+		return x, y, true
+	}
+	if x < 0 || x >= dimX {
+		if t == Bounded {
+			return 0, 0, false
+		}
+		x = mod(x, dimX)
+		if t == CrossSurface {
+			y = dimY - 1 - y
+		}
+	}
+	if y < 0 || y >= dimY {
+		if t == Bounded {
+			return 0, 0, false
+		}
+		y = mod(y, dimY)
+		if t == KleinBottle || t == CrossSurface {
+			x = dimX - 1 - x
+		}
+	}
+	return x, y, true
+}
@@ -0,0 +1,323 @@
+package life
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// A Flip mirrors a pattern.
+type Flip uint8
+
+const (
+	NoFlip Flip = iota
+	// Reverses the order of the columns.
+	FlipHorizontal
+	// Reverses the order of the rows.
+	FlipVertical
+)
+
+// Parses a rotation given in degrees clockwise, a multiple of 90, and returns
+// it as a number of quarter turns.
+func ParseRotation(rotation string) (int, error) {
+	degrees, err := strconv.Atoi(rotation)
+	if err != nil || degrees%90 != 0 {
+		return 0, errors.New("invalid rotation " + rotation + ", expected a multiple of 90 degrees")
+	}
+	return mod(degrees/90, 4), nil
+}
+
+// Parses a flip by name: none, h (horizontal) or v (vertical).
+func ParseFlip(flip string) (Flip, error) {
+	switch flip {
+	case "none":
+		return NoFlip, nil
+	case "h", "horizontal":
+		return FlipHorizontal, nil
+	case "v", "vertical":
+		return FlipVertical, nil
+	}
+	return NoFlip, errors.New("invalid flip " + flip + ", expected none, h or v")
+}
+
+// Turns the pattern clockwise by `quarterTurns` quarter turns.
+func (p *pattern) rotate(quarterTurns int) {
+	for turn := 0; turn < mod(quarterTurns, 4); turn++ {
+		for idx, c := range p.cells {
+			p.cells[idx] = Cell{c.Y, p.rows - 1 - c.X}
+		}
+		p.rows, p.cols = p.cols, p.rows
+	}
+}
+
+// Mirrors the pattern.
+func (p *pattern) flip(flip Flip) {
+	for idx, c := range p.cells {
+		switch flip {
+		case FlipHorizontal:
+			p.cells[idx].Y = p.cols - 1 - c.Y
+		case FlipVertical:
+			p.cells[idx].X = p.rows - 1 - c.X
+		}
+	}
+}
+
+// Swaps the rows and the columns of the pattern.
+func (p *pattern) transpose() {
+	for idx, c := range p.cells {
+		p.cells[idx] = Cell{c.Y, c.X}
+	}
+	p.rows, p.cols = p.cols, p.rows
+}
+
+// Surrounds the pattern with `margin` dead cells on each side.
+func (p *pattern) pad(margin int) {
+	for idx := range p.cells {
+		p.cells[idx].X += margin
+		p.cells[idx].Y += margin
+	}
+	p.rows, p.cols = p.rows+2*margin, p.cols+2*margin
+}
+
+// A Transform changes the live cells and the dimensions of a board.
+// Transforms are applied to the configuration of a game WithTransforms or to
+// its current state by Life.Transform.
+type Transform func(p *pattern) error
+
+// Turns the board clockwise by `quarterTurns` quarter turns.
+func Rotate(quarterTurns int) Transform {
+	return func(p *pattern) error {
+		p.rotate(quarterTurns)
+		return nil
+	}
+}
+
+// Mirrors the board.
+func Mirror(flip Flip) Transform {
+	return func(p *pattern) error {
+		p.flip(flip)
+		return nil
+	}
+}
+
+// Swaps the rows and the columns of the board.
+func Transpose() Transform {
+	return func(p *pattern) error {
+		p.transpose()
+		return nil
+	}
+}
+
+// Cuts the board to the bounding box of its live cells.
+func Crop() Transform {
+	return func(p *pattern) error {
+		normalize(p)
+		return nil
+	}
+}
+
+// Surrounds the board with `margin` dead cells on each side.
+func Pad(margin int) Transform {
+	return func(p *pattern) error {
+		if margin < 0 {
+			return errors.New("the padding must not be negative")
+		}
+		if margin > MaxBoardSize {
+			return errors.New("the padding must not exceed " + strconv.Itoa(MaxBoardSize))
+		}
+		if err := checkBoardSize(p.rows+2*margin, p.cols+2*margin); err != nil {
+			return err
+		}
+		p.pad(margin)
+		return nil
+	}
+}
+
+// Changes the dimensions of the board to `rows` and `cols`, keeping its top
+// left corner in place. The cells past the new edges are dropped.
+func Resize(rows, cols int) Transform {
+	return func(p *pattern) error {
+		if rows <= 0 || cols <= 0 {
+			return errors.New("the board must have positive dimensions")
+		}
+		if err := checkBoardSize(rows, cols); err != nil {
+			return err
+		}
+		cells := p.cells[:0]
+		for _, c := range p.cells {
+			if c.X < rows && c.Y < cols {
+				cells = append(cells, c)
+			}
+		}
+		p.cells, p.rows, p.cols = cells, rows, cols
+		return nil
+	}
+}
+
+// Moves the live cells `dx` rows down and `dy` columns right. Cells moved past
+// the edges of the board are dropped, wrapped around or kept depending on the
+// topology of the game.
+func Translate(dx, dy int) Transform {
+	return func(p *pattern) error {
+		for idx := range p.cells {
+			p.cells[idx].X += dx
+			p.cells[idx].Y += dy
+		}
+		return nil
+	}
+}
+
+// Parses a transform, one of:
+//   - rotate:<degrees>: turn clockwise by a multiple of 90 degrees
+//   - flip:<h|v>: mirror horizontally or vertically
+//   - transpose: swap the rows and the columns
+//   - crop: cut to the bounding box of the live cells
+//   - pad:<n>: surround with n dead cells on each side
+//   - resize:<rows>x<cols>: change the dimensions keeping the top left corner
+//   - translate:<dx>,<dy>: move the live cells dx rows down and dy columns
+//     right
+func ParseTransform(transform string) (Transform, error) {
+	name, argument, _ := strings.Cut(transform, ":")
+	invalid := errors.New("invalid transform " + transform)
+	switch name {
+	case "rotate":
+		quarterTurns, err := ParseRotation(argument)
+		if err != nil {
+			return nil, err
+		}
+		return Rotate(quarterTurns), nil
+	case "flip":
+		flip, err := ParseFlip(argument)
+		if err != nil {
+			return nil, err
+		}
+		return Mirror(flip), nil
+	case "transpose", "crop":
+		if argument != "" {
+			return nil, invalid
+		}
+		if name == "crop" {
+			return Crop(), nil
+		}
+		return Transpose(), nil
+	case "pad":
+		margin, err := strconv.Atoi(argument)
+		if err != nil {
+			return nil, invalid
+		}
+		return Pad(margin), nil
+	case "resize", "translate":
+		separator := "x"
+		if name == "translate" {
+			separator = ","
+		}
+		first, second, found := strings.Cut(argument, separator)
+		a, errA := strconv.Atoi(first)
+		b, errB := strconv.Atoi(second)
+		if !found || errA != nil || errB != nil {
+			return nil, invalid
+		}
+		if name == "resize" {
+			return Resize(a, b), nil
+		}
+		return Translate(a, b), nil
+	}
+	return nil, errors.New("unknown transform " + name)
+}
+
+// Applies `transforms` in order to the current state of the game, which may
+// change the dimensions of the board. The history of the game begins anew
+// and its status is detected anew. Resetting the game restores the board it
+// started with.
+// Fails if any of the transforms fails, in which case the game is unchanged.
+func (l *Life) Transform(transforms ...Transform) error {
+	p := &pattern{
+		rows:     l.dimX,
+		cols:     l.dimY,
+		cells:    l.currentState.Snapshot(),
+		hasBoard: true,
+	}
+	for _, transform := range transforms {
+		if err := transform(p); err != nil {
+			return err
+		}
+	}
+	if err := checkBoardSize(p.rows, p.cols); err != nil {
+		return err
+	}
+	engine, err := NewEngine(l.engine, EngineConfig{
+		Rows:     p.rows,
+		Cols:     p.cols,
+		Topology: l.topology,
+		Rule:     l.rule,
+		Jump:     l.jump,
+	})
+	if err != nil {
+		return err
+	}
+	for _, c := range p.cells {
+		engine.Set(c.X, c.Y, true)
+	}
+	l.currentState = engine
+	l.dimX, l.dimY = p.rows, p.cols
+	l.stats.amend(l.currentState.Population())
+	l.detector = newDetector()
+	l.observe()
+	l.restartHistory()
+	return nil
+}
+
+// Reads the predefined configuration `config` as a pattern cut to the
+// bounding box of its live cells.
+func loadPattern(config string) (*pattern, error) {
+	configFile, err := openConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	defer configFile.Close()
+	p, err := parseConfig(configFile)
+	if err != nil {
+		return nil, err
+	}
+	normalize(p)
+	return p, nil
+}
+
+// Brings to life the live cells of the predefined configuration `config`,
+// turned clockwise by `quarterTurns` quarter turns and then flipped, so that
+// the top left cell of their bounding box is at `x`, `y`. The other cells are
+// left as they are.
+// Fails if the configuration does not exist, the pattern does not fit on a
+// bounded board or is larger than a board whose edges are connected.
+func (l *Life) Place(config string, x, y, quarterTurns int, flip Flip) error {
+	p, err := loadPattern(config)
+	if err != nil {
+		return err
+	}
+	p.rotate(quarterTurns)
+	p.flip(flip)
+	return l.stamp(p, x, y)
+}
+
+// Brings to life the live cells of `p` so that its top left cell is at `x`,
+// `y`.
+func (l *Life) stamp(p *pattern, x, y int) error {
+	switch {
+	case l.topology == Unbounded:
+	case l.topology == Bounded:
+		if x < 0 || y < 0 || x+p.rows > l.dimX || y+p.cols > l.dimY {
+			return fmt.Errorf("the %dx%d pattern does not fit on the board at (%d, %d)",
+				p.rows, p.cols, x, y)
+		}
+	default:
+		if p.rows > l.dimX || p.cols > l.dimY {
+			return fmt.Errorf("the %dx%d pattern is larger than the board", p.rows, p.cols)
+		}
+	}
+	for _, c := range p.cells {
+		l.currentState.Set(x+c.X, y+c.Y, true)
+	}
+	l.edited()
+	return nil
+}
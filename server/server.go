@@ -6,11 +6,23 @@ import (
 	"LaaS/executor"
 	"LaaS/life"
 	"LaaS/server/session"
+	"LaaS/server/storage"
 	"LaaS/server/user"
 	"bufio"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"net"
+	"os"
+	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const connectionType = "tcp"
@@ -20,21 +32,62 @@ const port = ":8088"
 // and the sessions which the users have added.
 // The Server methods return a human readable string which describes the result
 // of the issued request - no matter if the operation has succeeded or failed.
+// Sessions running as fast as possible share the CPU budget of the server.
+// Servers restored from a store save every change to their users and sessions
+// to it, and their running sessions save themselves at checkpoints.
+// Requests are served concurrently: the lists of users, sessions and
+// connections are guarded by a mutex while each session guards its own game.
+// Once the server shuts down it serves no more requests.
 type Server struct {
-	sessions []*session.Session
-	users    []user.User
+	mutex       sync.RWMutex
+	sessions    []*session.Session
+	users       []*user.User
+	budget      *session.Budget
+	clock       session.Clock
+	store       *storage.Store
+	saving      sync.Mutex // guards store, held before mutex
+	checkpoints *session.Checkpoints
+	connections map[*connection]struct{}
+	closing     bool
+	requests    sync.WaitGroup
 }
 
-// Constructs a Server.
+// Constructs a Server whose sessions running as fast as possible may compute
+// a generation on every CPU at the same time.
 func NewServer() *Server {
+	return NewServerWithBudget(runtime.NumCPU())
+}
+
+// Constructs a Server whose sessions running as fast as possible may compute
+// at most `workers` generations at the same time.
+func NewServerWithBudget(workers int) *Server {
+	return NewServerWithClock(workers, session.SystemClock)
+}
+
+// Constructs a Server whose sessions run by `clock` and may compute at most
+// `workers` generations at the same time when running as fast as possible.
+func NewServerWithClock(workers int, clock session.Clock) *Server {
 	s := new(Server)
 	s.sessions = []*session.Session{}
+	s.budget = session.NewBudget(workers)
+	s.clock = clock
+	s.checkpoints = &session.Checkpoints{Save: s.saveSession}
+	s.connections = make(map[*connection]struct{})
 	return s
 }
 
+// Makes running sessions save themselves to the store of the server every
+// `generations` generations or every `interval`, whichever comes first. Zero
+// disables either limit. Must be called before the server serves requests.
+func (s *Server) checkpointEvery(generations uint64, interval time.Duration) {
+	s.checkpoints.Generations = generations
+	s.checkpoints.Interval = interval
+}
+
 // Implement the Executable interface for use with LaaS/executor.
 func (s *Server) AssertExecutable() {}
 
+// The mutex must be held when looking up sessions or users by index.
 func (s *Server) sessionIndex(name string) int {
 	for idx, session := range s.sessions {
 		if session.Name == name {
@@ -53,13 +106,139 @@ func (s *Server) userIndex(name string) int {
 	return -1
 }
 
+// Returns the session named `name`, nil if there is none.
+func (s *Server) findSession(name string) *session.Session {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if index := s.sessionIndex(name); index != -1 {
+		return s.sessions[index]
+	}
+	return nil
+}
+
+// Returns the user named `name`, nil if there is none.
+func (s *Server) findUser(name string) *user.User {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	if index := s.userIndex(name); index != -1 {
+		return s.users[index]
+	}
+	return nil
+}
+
+// Returns the session named `name` if `username` is its owner.
+func (s *Server) ownedSession(username, name string) (*session.Session, error) {
+	current := s.findSession(name)
+	if current == nil {
+		return nil, errors.New(session.NoSession(name))
+	}
+	if !current.Authorize(username) {
+		return nil, errors.New(user.NotAuthorized(username))
+	}
+	return current, nil
+}
+
+// Loads the users and sessions kept in `store`, running again the sessions
+// which were running when they were saved, and saves every later change to
+// the store. If the server crashed, the sessions which were running resume
+// from their last checkpoint and report the generations they lost.
+// Fails if the store cannot be read or any of its sessions cannot be
+// restored, in which case the server is unchanged.
+func (s *Server) restore(store *storage.Store) error {
+	storedUsers, err := store.LoadUsers()
+	if err != nil {
+		return err
+	}
+	records, err := store.LoadSessions()
+	if err != nil {
+		return err
+	}
+	users := make([]*user.User, len(storedUsers))
+	owners := make(map[string]*user.User)
+	for idx, stored := range storedUsers {
+		users[idx] = user.RestoreUser(stored.Name, stored.PasswordHash)
+		owners[stored.Name] = users[idx]
+	}
+	sessions := make([]*session.Session, len(records))
+	for idx, record := range records {
+		if sessions[idx], err = session.Restore(record, owners[record.Owner], s.clock); err != nil {
+			return err
+		}
+		sessions[idx].Budget = s.budget
+		sessions[idx].Checkpoints = s.checkpoints
+		if store.Crashed() && record.Running {
+			sessions[idx].MarkRecovered()
+		}
+	}
+
+	s.saving.Lock()
+	s.mutex.Lock()
+	s.users, s.sessions, s.store = users, sessions, store
+	s.mutex.Unlock()
+	s.saving.Unlock()
+	for idx, record := range records {
+		if record.Running {
+			sessions[idx].Run()
+		}
+	}
+	return nil
+}
+
+// Saves the users of the server to its store, if it has one.
+func (s *Server) saveUsers() {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	if s.store == nil {
+		return
+	}
+	s.mutex.RLock()
+	users := make([]storage.User, len(s.users))
+	for idx, user := range s.users {
+		users[idx] = storage.User{Name: user.Name, PasswordHash: user.PasswordHash()}
+	}
+	s.mutex.RUnlock()
+	if err := s.store.SaveUsers(users); err != nil {
+		fmt.Println("saving the users failed:", err)
+	}
+}
+
+// Saves `current` to the store of the server, if it has one and the session
+// has not been killed.
+func (s *Server) saveSession(current *session.Session) {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	if s.store == nil || s.findSession(current.Name) != current {
+		return
+	}
+	if err := s.store.SaveSession(current.Record()); err != nil {
+		fmt.Println("saving session", current.Name, "failed:", err)
+	}
+}
+
+// Removes the session named `name` from the store of the server, if it has
+// one.
+func (s *Server) deleteSession(name string) {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	if s.store == nil {
+		return
+	}
+	if err := s.store.DeleteSession(name); err != nil {
+		fmt.Println("deleting session", name, "failed:", err)
+	}
+}
+
 // Registers a user with the server.
 // Fails if the username is already taken.
 func (s *Server) Register(username, password string) string {
+	s.mutex.Lock()
 	if s.userIndex(username) != -1 {
+		s.mutex.Unlock()
 		return "user " + username + " already exists"
 	}
-	s.users = append(s.users, *user.NewUser(username, password))
+	s.users = append(s.users, user.NewUser(username, password))
+	s.mutex.Unlock()
+	s.saveUsers()
 	return "registered user " + username
 }
 
@@ -68,11 +247,10 @@ func (s *Server) Register(username, password string) string {
 //   - a user with the name `username` does not exist
 //   - the password `password` does not match the password of the user
 func (s *Server) Login(username, password string) string {
-	index := s.userIndex(username)
-	if index == -1 {
+	user := s.findUser(username)
+	if user == nil {
 		return "user " + username + " does not exist"
 	}
-	user := s.users[index]
 	if user.Authorize(password) {
 		return "user " + username + " logged in"
 	} else {
@@ -81,87 +259,275 @@ func (s *Server) Login(username, password string) string {
 }
 
 // Creates a new session whose owner is the user issuing the request.
-// Fails if a sessions with the same name already exists.
+// Fails if:
+//   - a sessions with the same name already exists
+//   - the user issuing the request does not exist
 func (s *Server) Add(username, name string) string {
+	s.mutex.Lock()
 	if s.sessionIndex(name) != -1 {
+		s.mutex.Unlock()
 		return "session with the name " + name + " already exists"
 	}
-	owner := &s.users[s.userIndex(username)]
-	s.sessions = append(s.sessions, session.NewSession(name, owner))
+	ownerIndex := s.userIndex(username)
+	if ownerIndex == -1 {
+		s.mutex.Unlock()
+		return "user " + username + " does not exist"
+	}
+	added := session.NewSessionWithClock(name, s.users[ownerIndex], s.clock)
+	added.Budget = s.budget
+	added.Checkpoints = s.checkpoints
+	s.sessions = append(s.sessions, added)
+	s.mutex.Unlock()
+	s.saveSession(added)
 	return "successfully created session " + name
 }
 
+// Creates a new session owned by the user issuing the request whose game is a
+// copy of the game of the session named `source`, including its rule,
+// topology, statistics and history. Passing `start` as the last argument
+// copies the starting configuration of the game instead of its current state.
+// The new session is stopped.
+// Fails if:
+//   - a sessions with the name `source` does not exist
+//   - the user issuing the request does not exist
+//   - the argument following the names is anything but `start`
+//   - the session `source` had not been started
+//   - a session with the name `name` already exists
+func (s *Server) Fork(username, source, name string, from ...string) string {
+	original := s.findSession(source)
+	if original == nil {
+		return session.NoSession(source)
+	}
+	owner := s.findUser(username)
+	if owner == nil {
+		return "user " + username + " does not exist"
+	}
+	fromStart := len(from) == 1 && from[0] == "start"
+	if len(from) > 0 && !fromStart {
+		return "fork takes only the optional argument start"
+	}
+
+	forked, err := original.Fork(name, owner, fromStart)
+	if err != nil {
+		return err.Error()
+	}
+	s.mutex.Lock()
+	if s.sessionIndex(name) != -1 {
+		s.mutex.Unlock()
+		return "session with the name " + name + " already exists"
+	}
+	s.sessions = append(s.sessions, forked)
+	s.mutex.Unlock()
+	s.saveSession(forked)
+	return "successfully forked session " + source + " into " + name
+}
+
 // Permanently removes a session from the server.
 // Fails if
 //   - no session with the name `name` exists
 //   - the user issuing the request is not the owner of the session
 func (s *Server) Kill(username, name string) string {
+	s.mutex.Lock()
 	index := s.sessionIndex(name)
 	if index == -1 {
+		s.mutex.Unlock()
 		return session.NoSession(name)
 	}
 	current := s.sessions[index]
 	if !current.Authorize(username) {
+		s.mutex.Unlock()
 		return user.NotAuthorized(username)
 	}
-	if current.IsRunning {
-		current.Stop()
-	}
 	last := len(s.sessions) - 1
 	s.sessions[index] = s.sessions[last]
+	s.sessions[last] = nil
 	s.sessions = s.sessions[:last]
+	s.mutex.Unlock()
+
+	// the session is no longer reachable, it is closed outside the lock so
+	// that other requests are not held up waiting for it to stop, and so
+	// that requests which found it before cannot run it again
+	current.Close()
+	s.deleteSession(name)
 	return "session " + name + " successfully killed"
 }
 
+// The number of steps sessions remember unless configured otherwise.
+const defaultHistory = 256
+
+// The options of a Start request which configure the session rather than the
+// game.
+type sessionOptions struct {
+	autoStop bool
+	interval time.Duration
+	until    []session.Condition
+}
+
+// Parses the `until=<condition>` options of a Start or Resume request, see
+// session.ParseCondition.
+func parseCondition(value string, settings *sessionOptions) error {
+	condition, err := session.ParseCondition(value)
+	if err != nil {
+		return err
+	}
+	settings.until = append(settings.until, condition)
+	return nil
+}
+
+// Translates the `key=value` options of a Start request into options used when
+// constructing the game and options of the session. The supported options are:
+//   - margin=<cells>: the number of dead cells surrounding the pattern
+//   - rule=<rule>: the rule of the game, overriding that of the config
+//   - topology=<topology>: how the edges of the board are connected
+//   - engine=<engine>: the engine running the game
+//   - jump=<n>: advance the game by 2^n generations every step (hashlife only)
+//   - autostop=<bool>: stop the session once the game dies out or becomes
+//     periodic
+//   - speed=<speed>: generations per second, the time between generations or
+//     max, see session.ParseSpeed
+//   - history=<steps>: the number of steps which can be rewound
+//   - until=<condition>: pause the session once the condition is met, may be
+//     given several times
+//   - transform=<transform>: transform the board described by the config, may
+//     be given several times, see life.ParseTransform
+func parseStartOptions(options []string) ([]life.Option, sessionOptions, error) {
+	result := []life.Option{life.WithHistory(defaultHistory)}
+	settings := sessionOptions{interval: session.DefaultInterval}
+	for _, option := range options {
+		key, value, found := strings.Cut(option, "=")
+		if !found {
+			return nil, settings, errors.New("malformed option " + option)
+		}
+		switch key {
+		case "margin":
+			margin, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, settings, errors.New("invalid margin " + value)
+			}
+			result = append(result, life.WithMargin(margin))
+		case "rule":
+			rule, err := life.ParseRule(value)
+			if err != nil {
+				return nil, settings, err
+			}
+			result = append(result, life.WithRule(rule))
+		case "topology":
+			topology, err := life.ParseTopology(value)
+			if err != nil {
+				return nil, settings, err
+			}
+			result = append(result, life.WithTopology(topology))
+		case "engine":
+			result = append(result, life.WithEngine(value))
+		case "jump":
+			jump, err := strconv.ParseUint(value, 10, 8)
+			if err != nil {
+				return nil, settings, errors.New("invalid jump " + value)
+			}
+			result = append(result, life.WithJump(uint(jump)))
+		case "history":
+			depth, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, settings, errors.New("invalid history " + value)
+			}
+			result = append(result, life.WithHistory(depth))
+		case "autostop":
+			autoStop, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, settings, errors.New("invalid autostop " + value)
+			}
+			settings.autoStop = autoStop
+		case "speed":
+			interval, err := session.ParseSpeed(value)
+			if err != nil {
+				return nil, settings, err
+			}
+			settings.interval = interval
+		case "until":
+			if err := parseCondition(value, &settings); err != nil {
+				return nil, settings, err
+			}
+		case "transform":
+			transform, err := life.ParseTransform(value)
+			if err != nil {
+				return nil, settings, err
+			}
+			result = append(result, life.WithTransforms(transform))
+		default:
+			return nil, settings, errors.New("unknown option " + key)
+		}
+	}
+	return result, settings, nil
+}
+
 // Loads a game configuration in the session named `name` and starts the game.
+// The configuration is either predefined or a random soup described by
+// `random:<width>x<height>:<density>:<seed>[:C2|C4|D8]`.
+// Any number of `key=value` options may follow the config, see
+// parseStartOptions.
 // Fails if:
 //   - a sessions with the name `name` does not exist
 //   - the user issuing the request is not the owner of the session
 //   - the session has already been started
 //   - the config `config` does not exist
-func (s *Server) Start(username, name, config string) string {
-	index := s.sessionIndex(name)
-	if index == -1 {
-		return session.NoSession(name)
-	}
-	current := s.sessions[index]
-	if !current.Authorize(username) {
-		return user.NotAuthorized(username)
+//   - any of the options is invalid
+func (s *Server) Start(username, name, config string, options ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
 	}
-	if current.IsRunning {
+	if current.IsRunning() {
 		return "session " + name + " is already running"
 	}
 
-	newLife, err := life.NewLife(config)
+	opts, settings, err := parseStartOptions(options)
+	if err != nil {
+		return err.Error()
+	}
+	newLife, err := life.NewLife(config, opts...)
 	if err != nil {
 		return err.Error()
 	}
 
-	current.CurrState = newLife
-	current.Run()
-
+	err = current.Start(config, newLife, session.Settings{
+		AutoStop: settings.autoStop,
+		Interval: settings.interval,
+		Until:    settings.until,
+	})
+	if err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
 	return "successfully started session " + name
 }
 
-// Resumes a stopped session.
+// Resumes a stopped session. Any number of `until=<condition>` options may
+// follow the name, replacing the conditions the session was previously
+// running until.
 // Fails if:
 //   - a sessions with the name `name` does not exist
 //   - the user issuing the request is not the owner of the session
-//   - the session is currently running
-func (s *Server) Resume(username, name string) string {
-	index := s.sessionIndex(name)
-	if index == -1 {
-		return session.NoSession(name)
+//   - any of the options is invalid
+//   - the session had not been started or is currently running
+func (s *Server) Resume(username, name string, options ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
 	}
-	current := s.sessions[index]
-	if !current.Authorize(username) {
-		return user.NotAuthorized(username)
+	var settings sessionOptions
+	for _, option := range options {
+		key, value, found := strings.Cut(option, "=")
+		if !found || key != "until" {
+			return "resume takes only until=<condition> options"
+		}
+		if err := parseCondition(value, &settings); err != nil {
+			return err.Error()
+		}
 	}
-	if current.IsRunning {
-		return "session " + name + " is already running"
+	if err := current.Resume(settings.until); err != nil {
+		return err.Error()
 	}
-	current.Run()
-
+	s.saveSession(current)
 	return "successfully resumed session " + name
 }
 
@@ -171,90 +537,702 @@ func (s *Server) Resume(username, name string) string {
 //   - the user issuing the request is not the owner of the session
 //   - the session is not currently running
 func (s *Server) Stop(username, session string) string {
-	index := s.sessionIndex(session)
-	if index == -1 {
-		return "no session with the name " + session + " found"
-	}
-	current := s.sessions[index]
-	if !current.Authorize(username) {
-		return user.NotAuthorized(username)
+	current, err := s.ownedSession(username, session)
+	if err != nil {
+		return err.Error()
 	}
-	if !current.IsRunning {
-		return "session " + session + " is already stopped"
+	if err := current.Stop(); err != nil {
+		return err.Error()
 	}
-	current.Stop()
+	s.saveSession(current)
 	return "session " + session + " successfully stopped"
 }
 
+// Changes the speed of the session named `name`. The speed is given as a
+// number of generations per second, as the time between generations or as
+// max, to run the game as fast as the CPU budget of the server allows.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the speed is invalid
+func (s *Server) Speed(username, name, speed string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	interval, err := session.ParseSpeed(speed)
+	if err != nil {
+		return err.Error()
+	}
+	current.SetInterval(interval)
+	s.saveSession(current)
+	return "session " + name + " now advances " + session.FormatSpeed(interval)
+}
+
 // Returns the current state of the running game associated with the session
 // named `session`. Any user can watch any session.
+// The viewer may fix the region of the board which is shown by passing the
+// row and column of its top left cell followed by its number of rows and
+// columns. Otherwise the whole board is shown, or, for unbounded universes,
+// the region containing the live cells.
 // Fails if:
 //   - a sessions with the name `name` does not exist
+//   - the viewport is invalid
 //   - the session had not been started
-func (s *Server) Watch(_, session string) string {
-	index := s.sessionIndex(session)
-	if index == -1 {
+func (s *Server) Watch(_, session string, viewport ...string) string {
+	current := s.findSession(session)
+	if current == nil {
 		return "no session with the name " + session + " found"
 	}
-	if s.sessions[index].CurrState == nil {
-		return "the session " + session + " has not been started"
+	var region [4]int
+	if len(viewport) > 0 {
+		var err error
+		if region, err = parseViewport(viewport); err != nil {
+			return err.Error()
+		}
+	}
+	var board string
+	err := current.View(func(game *life.Life) {
+		board = describeGame(game)
+		if len(viewport) == 0 {
+			board += game.Printable()
+		} else {
+			board += game.PrintableRegion(region[0], region[1], region[2], region[3])
+		}
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return board
+}
+
+// Returns the rule, topology, statistics and status of `game`, shown above its
+// board.
+func describeGame(game *life.Life) string {
+	description := fmt.Sprintf("rule %s, topology %s", game.Rule(), game.Topology())
+	if game.GenerationsPerStep() > 1 {
+		description += fmt.Sprintf(", %d generations per step", game.GenerationsPerStep())
+	}
+	description += "\n" + game.Stats().String()
+	return description + "\n" + game.Status().String() + "\n"
+}
+
+// Returns the current board of `current` along with the description of its
+// game.
+func showBoard(current *session.Session) string {
+	var board string
+	err := current.View(func(game *life.Life) {
+		board = describeGame(game) + game.Printable()
+	})
+	if err != nil {
+		return err.Error()
 	}
-	return s.sessions[index].CurrState.Printable()
+	return board
+}
+
+// Applies `edit` to the game of the stopped session `current`, saves the
+// session and returns the resulting board.
+func (s *Server) editBoard(current *session.Session, edit func(game *life.Life) error) string {
+	var board string
+	err := current.Edit(func(game *life.Life) error {
+		if err := edit(game); err != nil {
+			return err
+		}
+		board = describeGame(game) + game.Printable()
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	return board
+}
+
+// Advances the stopped session named `name` by `n` steps, one unless given,
+// and returns the resulting board.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - `n` is not a positive number of at most life.MaxSteps
+func (s *Server) Step(username, name string, n ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	steps := 1
+	if len(n) > 1 {
+		return "step takes at most one number of steps"
+	} else if len(n) == 1 {
+		var err error
+		steps, err = strconv.Atoi(n[0])
+		if err != nil || steps <= 0 || steps > life.MaxSteps {
+			return "the number of steps must be between 1 and " + strconv.Itoa(life.MaxSteps)
+		}
+	}
+	if err := current.Step(steps); err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	return showBoard(current)
+}
+
+// Parses the row `x` and column `y` of a cell.
+func parseCell(x, y string) (int, int, error) {
+	row, errX := strconv.Atoi(x)
+	col, errY := strconv.Atoi(y)
+	if errX != nil || errY != nil {
+		return 0, 0, errors.New("invalid cell " + x + " " + y)
+	}
+	return row, col, nil
+}
+
+// Brings the cell at row `x` and column `y` of the stopped session named
+// `name` to life or kills it, depending on whether `state` is alive or dead,
+// and returns the resulting board.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - the cell is invalid or outside the board
+//   - the state is neither alive nor dead
+func (s *Server) Set(username, name, x, y, state string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	row, col, err := parseCell(x, y)
+	if err != nil {
+		return err.Error()
+	}
+	if state != "alive" && state != "dead" {
+		return "the state of a cell is either alive or dead"
+	}
+	return s.editBoard(current, func(game *life.Life) error {
+		return game.SetCell(row, col, state == "alive")
+	})
+}
+
+// Kills the cell at row `x` and column `y` of the stopped session named
+// `name` if it is alive and brings it to life otherwise, and returns the
+// resulting board.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - the cell is invalid or outside the board
+func (s *Server) Toggle(username, name, x, y string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	row, col, err := parseCell(x, y)
+	if err != nil {
+		return err.Error()
+	}
+	return s.editBoard(current, func(game *life.Life) error {
+		return game.ToggleCell(row, col)
+	})
+}
+
+// Kills all the cells of the stopped session named `name`.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+func (s *Server) Clear(username, name string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	err = current.Edit(func(game *life.Life) error {
+		game.Clear()
+		return nil
+	})
+	if err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	return "session " + name + " successfully cleared"
+}
+
+// Replaces the cells of the stopped session named `name` with random ones,
+// each alive with probability `density`, and returns the resulting board.
+// The random cells are reproducible by passing the same `seed`, a seed is
+// picked unless given.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - the density is not a number between 0 and 1
+//   - the seed is not a number
+func (s *Server) FillRandom(username, name, density string, seed ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	probability, err := strconv.ParseFloat(density, 64)
+	if err != nil {
+		return "invalid density " + density
+	}
+	randomSeed := s.clock.Now().UnixNano()
+	if len(seed) > 1 {
+		return "fill-random takes at most one seed"
+	} else if len(seed) == 1 {
+		randomSeed, err = strconv.ParseInt(seed[0], 10, 64)
+		if err != nil {
+			return "invalid seed " + seed[0]
+		}
+	}
+	return s.editBoard(current, func(game *life.Life) error {
+		return game.FillRandom(probability, randomSeed)
+	})
+}
+
+// Places the live cells of the predefined configuration `pattern` on the board
+// of the session named `name` so that the top left cell of their bounding box
+// is at row `x` and column `y`, and returns the resulting board. The pattern
+// may be rotated clockwise by a multiple of 90 degrees and then flipped
+// horizontally (h) or vertically (v). Running sessions keep running.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the pattern does not exist
+//   - the position, rotation or flip is invalid
+//   - the pattern does not fit on the board
+func (s *Server) Place(username, name, pattern, x, y string, orientation ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	row, col, err := parseCell(x, y)
+	if err != nil {
+		return err.Error()
+	}
+	if len(orientation) > 2 {
+		return "place takes at most a rotation and a flip"
+	}
+	quarterTurns, flip := 0, life.NoFlip
+	if len(orientation) > 0 {
+		if quarterTurns, err = life.ParseRotation(orientation[0]); err != nil {
+			return err.Error()
+		}
+	}
+	if len(orientation) > 1 {
+		if flip, err = life.ParseFlip(orientation[1]); err != nil {
+			return err.Error()
+		}
+	}
+
+	var board string
+	err = current.Update(func(game *life.Life) error {
+		err := game.Place(pattern, row, col, quarterTurns, flip)
+		board = describeGame(game) + game.Printable()
+		return err
+	})
+	if err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	return board
+}
+
+// Applies the `transforms` in order to the board of the stopped session named
+// `name` and returns the resulting board, see life.ParseTransform. The
+// history of the session begins anew.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - no transform is given or any of the transforms is invalid
+func (s *Server) Transform(username, name string, transforms ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	if len(transforms) == 0 {
+		return "transform takes at least one transform"
+	}
+	parsed := make([]life.Transform, len(transforms))
+	for idx, transform := range transforms {
+		if parsed[idx], err = life.ParseTransform(transform); err != nil {
+			return err.Error()
+		}
+	}
+	return s.editBoard(current, func(game *life.Life) error {
+		return game.Transform(parsed...)
+	})
+}
+
+// Moves the stopped session named `name` back by `n` steps and returns the
+// resulting board.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - the session keeps no history or its history does not reach that far
+func (s *Server) Rewind(username, name, n string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	steps, err := strconv.Atoi(n)
+	if err != nil || steps < 0 {
+		return "the number of steps must not be negative"
+	}
+	return s.editBoard(current, func(game *life.Life) error {
+		return game.Rewind(steps)
+	})
+}
+
+// Moves the stopped session named `name` to `generation`, backward within its
+// history or forward by computing the generations, and returns the resulting
+// board.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the session had not been started
+//   - the session is running
+//   - the session keeps no history, the generation is older than it or too
+//     far past it
+func (s *Server) Seek(username, name, generation string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	target, err := strconv.ParseUint(generation, 10, 64)
+	if err != nil {
+		return "invalid generation " + generation
+	}
+	if err := current.Seek(target); err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	return showBoard(current)
+}
+
+// Restores the starting configuration of the session named `name`, counting
+// generations and keeping statistics anew. Running sessions are stopped, the
+// session is restarted if `run` is passed as the last argument.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the user issuing the request is not the owner of the session
+//   - the argument following the name is anything but `run`
+//   - the session had not been started
+func (s *Server) Reset(username, name string, run ...string) string {
+	current, err := s.ownedSession(username, name)
+	if err != nil {
+		return err.Error()
+	}
+	restart := len(run) == 1 && run[0] == "run"
+	if len(run) > 0 && !restart {
+		return "reset takes only the optional argument run"
+	}
+	if err := current.Reset(restart); err != nil {
+		return err.Error()
+	}
+	s.saveSession(current)
+	if restart {
+		return "session " + name + " successfully reset and restarted"
+	}
+	return "session " + name + " successfully reset"
+}
+
+// Returns the generation, population, births and deaths during the last step,
+// bounding box and recent populations of the game associated with the session
+// named `session`. Any user can see the statistics of any session.
+// Fails if:
+//   - a sessions with the name `name` does not exist
+//   - the session had not been started
+func (s *Server) Stats(_, session string) string {
+	current := s.findSession(session)
+	if current == nil {
+		return "no session with the name " + session + " found"
+	}
+	var stats string
+	err := current.View(func(game *life.Life) {
+		stats = game.Stats().Detailed()
+	})
+	if err != nil {
+		return err.Error()
+	}
+	return stats
+}
+
+func parseViewport(viewport []string) ([4]int, error) {
+	var region [4]int
+	if len(viewport) != len(region) {
+		return region, errors.New("a viewport is described by x, y, rows and columns")
+	}
+	for idx, value := range viewport {
+		var err error
+		region[idx], err = strconv.Atoi(value)
+		if err != nil {
+			return region, errors.New("invalid viewport " + strings.Join(viewport, " "))
+		}
+	}
+	if region[2] <= 0 || region[3] <= 0 {
+		return region, errors.New("the viewport must have positive dimensions")
+	}
+	return region, nil
 }
 
 // Returns information for all the sessions on the server.
 func (s *Server) List() string {
+	s.mutex.RLock()
+	sessions := slices.Clone(s.sessions)
+	s.mutex.RUnlock()
 	var listing strings.Builder
-	for _, session := range s.sessions {
+	for _, session := range sessions {
 		listing.WriteString(session.GetStringRepresentation())
 		listing.WriteString("\n")
 	}
-	listing.WriteString(fmt.Sprintf("\n%d total", len(s.sessions)))
+	listing.WriteString(fmt.Sprintf("\n%d total", len(sessions)))
 	return listing.String()
 }
 
-func (s *Server) handleRequest(connection *net.Conn) {
-	connectionAddress := (*connection).RemoteAddr().String()
+// The response to requests sent while the server shuts down, also sent to
+// every connected client when it begins to.
+const shuttingDown = "the server is shutting down"
+
+// A connection of a client to the server. Responses are written whole, one at
+// a time, as the server may notify the client while serving its request.
+type connection struct {
+	net.Conn
+	writing sync.Mutex
+}
+
+func (c *connection) respond(response string) {
+	c.writing.Lock()
+	defer c.writing.Unlock()
+	c.Write([]byte(response + "\000"))
+}
+
+// Registers `c` as connected. Reports false if the server is shutting down.
+func (s *Server) connect(c *connection) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closing {
+		return false
+	}
+	s.connections[c] = struct{}{}
+	return true
+}
+
+func (s *Server) disconnect(c *connection) {
+	s.mutex.Lock()
+	delete(s.connections, c)
+	s.mutex.Unlock()
+	c.Close()
+}
+
+// Registers a request as being served. Reports false if the server is
+// shutting down.
+func (s *Server) beginRequest() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closing {
+		return false
+	}
+	s.requests.Add(1)
+	return true
+}
+
+func (s *Server) handleRequest(c *connection) {
+	defer s.disconnect(c)
+	if !s.connect(c) {
+		c.respond(shuttingDown)
+		return
+	}
+	connectionAddress := c.RemoteAddr().String()
 	fmt.Println("serving", connectionAddress)
+	reader := bufio.NewReader(c)
 	var response string
 	for {
-		received, err := bufio.NewReader(*connection).ReadString('\000')
+		received, err := reader.ReadString('\000')
 		if err != nil {
 			fmt.Println(err)
 			return
 		}
-		request := strings.TrimRight(string(received), "\000")
-		execResult, err := executor.Execute(s, request)
-		if err != nil {
-			fmt.Println(err)
-			response = "internal server error"
-		} else {
-			response = execResult[0].Interface().(string)
+		if !s.beginRequest() {
+			c.respond(shuttingDown)
+			return
 		}
-		(*connection).Write([]byte(response + "\000"))
+		request := strings.TrimRight(string(received), "\000")
+		response = s.execute(request)
+		s.requests.Done()
+		c.respond(response)
 		if !strings.HasPrefix(request, "watch") {
 			fmt.Println(connectionAddress, "-", response)
 		}
 	}
 }
 
+// Executes `request` and returns the response to it. Requests which fail to
+// execute, or panic while executing, are answered with an internal error
+// without affecting the other requests.
+func (s *Server) execute(request string) (response string) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			fmt.Println("serving", request, "panicked:", recovered)
+			response = "internal server error"
+		}
+	}()
+	response, err := executor.ExecuteCommand(s, request)
+	if err != nil {
+		fmt.Println(err)
+		return "internal server error"
+	}
+	return response
+}
+
+// Serves the clients connecting to `listener` until it is closed.
+func (s *Server) serve(listener net.Listener) error {
+	for {
+		c, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handleRequest(&connection{Conn: c})
+	}
+}
+
+// Shuts the server down: no more requests are served, the connected clients
+// are told so and disconnected, the requests being served are finished and
+// the running sessions are stopped. Servers with a store save all their
+// sessions, marking those which were running to run again once restored, and
+// close the store.
+// Fails if `ctx` is done first or saving fails, in which case the sessions
+// which could not be saved resume from their last checkpoint once restored.
+func (s *Server) shutdown(ctx context.Context) error {
+	s.mutex.Lock()
+	s.closing = true
+	connections := make([]*connection, 0, len(s.connections))
+	for c := range s.connections {
+		connections = append(connections, c)
+	}
+	s.mutex.Unlock()
+	for _, c := range connections {
+		c.respond(shuttingDown)
+		c.Close()
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		s.requests.Wait()
+		s.mutex.RLock()
+		sessions := slices.Clone(s.sessions)
+		s.mutex.RUnlock()
+		stopped <- s.stopAndSave(sessions)
+	}()
+	select {
+	case err := <-stopped:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stops `sessions` at the same time, then saves them and closes the store.
+func (s *Server) stopAndSave(sessions []*session.Session) error {
+	wasRunning := make([]bool, len(sessions))
+	var wg sync.WaitGroup
+	for idx, current := range sessions {
+		wg.Add(1)
+		go func(idx int, current *session.Session) {
+			defer wg.Done()
+			wasRunning[idx] = current.Stop() == nil
+		}(idx, current)
+	}
+	wg.Wait()
+
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	if s.store == nil {
+		return nil
+	}
+	var errs []error
+	for idx, current := range sessions {
+		record := current.Record()
+		record.Running = wasRunning[idx]
+		if err := s.store.SaveSession(record); err != nil {
+			errs = append(errs, errors.New("saving session "+current.Name+" failed: "+err.Error()))
+		}
+	}
+	if len(errs) == 0 {
+		errs = append(errs, s.store.Close())
+	}
+	s.store = nil
+	return errors.Join(errs...)
+}
+
+var cpuBudget = flag.Int("cpu-budget", runtime.NumCPU(),
+	"the number of generations sessions running as fast as possible may compute at the same time")
+var dataDir = flag.String("data-dir", "data",
+	"the directory users and sessions are kept in across restarts, none if empty")
+var checkpointGenerations = flag.Uint64("checkpoint-generations", 1000,
+	"the number of generations after which running sessions are saved, never if 0")
+var checkpointInterval = flag.Duration("checkpoint-interval", 30*time.Second,
+	"the time after which running sessions are saved, never if 0")
+var shutdownTimeout = flag.Duration("shutdown-timeout", 10*time.Second,
+	"the time the server may take to shut down on SIGINT or SIGTERM")
+
 func main() {
+	flag.Parse()
 	l, err := net.Listen(connectionType, port)
 	if err != nil {
 		fmt.Println(err)
 		return
 	}
-	defer l.Close()
-
-	s := NewServer()
 
-	for {
-		c, err := l.Accept()
+	s := NewServerWithBudget(*cpuBudget)
+	s.checkpointEvery(*checkpointGenerations, *checkpointInterval)
+	if *dataDir != "" {
+		store, err := storage.Open(*dataDir)
+		if err == nil {
+			err = s.restore(store)
+		}
 		if err != nil {
 			fmt.Println(err)
+			l.Close()
 			return
 		}
-		defer c.Close()
-		go s.handleRequest(&c)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	served := make(chan error, 1)
+	go func() {
+		served <- s.serve(l)
+	}()
+	select {
+	case err := <-served:
+		fmt.Println(err)
+	case received := <-signals:
+		fmt.Println("received", received, "- shutting down")
+	}
+	l.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	go func() {
+		// a second signal exits at once
+		<-signals
+		cancel()
+	}()
+	if err := s.shutdown(ctx); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
 	}
 }
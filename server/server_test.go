@@ -1,12 +1,21 @@
 package main
 
 import (
+	"LaaS/executor"
 	"LaaS/life"
 	"LaaS/server/session"
+	"LaaS/server/storage"
 	"LaaS/server/user"
+	"bufio"
+	"context"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -21,16 +30,50 @@ func assert(actual, expected string, t *testing.T) {
 	}
 }
 
-func getTestSession() *session.Session {
+// Like assert, but may be called from goroutines other than the test.
+func expect(actual, expected string, t *testing.T) {
+	if expected != actual {
+		t.Errorf("expected: %s, actual: %s", expected, actual)
+	}
+}
+
+func getTestSession(clock session.Clock) *session.Session {
 	u := user.NewUser(test_user, test_password)
-	s := session.NewSession("test_session", u)
+	s := session.NewSessionWithClock("test_session", u, clock)
 	nl, _ := life.NewLife("pulsar")
-	s.CurrState = nl
+	s.Load("pulsar", nl)
 	return s
 }
 
+// Returns a copy of the game of `current`, which may be inspected while the
+// session runs.
+func snapshot(current *session.Session) *life.Life {
+	var game *life.Life
+	current.View(func(running *life.Life) {
+		game = running.Clone()
+	})
+	return game
+}
+
+// Returns a clock which only moves forward when advanced by the test.
+func getTestClock() *session.FakeClock {
+	return session.NewFakeClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+}
+
+// Advances the fake clock of `s` by the default interval `n` times, each time
+// once its running session waits for the next generation, and returns once
+// the session waits again.
+func tick(s *Server, n int) {
+	clock := s.clock.(*session.FakeClock)
+	for i := 0; i < n; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(session.DefaultInterval)
+	}
+	clock.BlockUntil(1)
+}
+
 func getTestServer() *Server {
-	s := NewServer()
+	s := NewServerWithClock(runtime.NumCPU(), getTestClock())
 	s.Register(test_user, test_password)
 	for i := 0; i < 10; i++ {
 		s.Add(test_user, fmt.Sprintf("test_session%d", i))
@@ -40,19 +83,28 @@ func getTestServer() *Server {
 
 func TestSessionRunProperlyStops(t *testing.T) {
 	t.Parallel()
-	s := getTestSession()
+	clock := getTestClock()
+	s := getTestSession(clock)
 	s.Run()
-	time.Sleep(time.Second)
+	for i := 0; i < 3; i++ {
+		clock.BlockUntil(1)
+		clock.Advance(session.DefaultInterval)
+	}
+	clock.BlockUntil(1)
 	s.Stop()
-	time.Sleep(time.Second)
-	if s.IsRunning {
+	if s.IsRunning() {
 		t.Fatal("expected session to stop running, it keeps going")
 	}
+	s.View(func(game *life.Life) {
+		if game.Generation() != 3 {
+			t.Fatalf("expected 3 generations in 3 intervals, got %d", game.Generation())
+		}
+	})
 }
 
 func TestSessionAuthorize(t *testing.T) {
 	t.Parallel()
-	s := getTestSession()
+	s := getTestSession(session.SystemClock)
 	if !s.Authorize(test_user) {
 		t.Fatal("authorization with correct credentials failed")
 	}
@@ -126,8 +178,7 @@ func TestStartProper(t *testing.T) {
 	expected := "successfully started session " + test_session
 	assert(result, expected, t)
 
-	time.Sleep(time.Second)
-	if !s.sessions[0].IsRunning {
+	if !s.sessions[0].IsRunning() {
 		t.Fatal("session is not running")
 	}
 }
@@ -166,6 +217,23 @@ func TestKillRunning(t *testing.T) {
 	assert(result, expected, t)
 }
 
+func TestKilledSessionDoesNotRun(t *testing.T) {
+	t.Parallel()
+	s := getTestSession(getTestClock())
+	s.Close()
+	game, _ := life.NewLife("pulsar")
+	expected := session.NoSession(s.Name)
+	if err := s.Start("pulsar", game, session.Settings{}); err == nil || err.Error() != expected {
+		t.Fatalf("expected starting a killed session to fail, got %v", err)
+	}
+	if err := s.Run(); err == nil || err.Error() != expected {
+		t.Fatalf("expected running a killed session to fail, got %v", err)
+	}
+	if err := s.Reset(true); err == nil || s.IsRunning() {
+		t.Fatal("expected restarting a killed session to fail")
+	}
+}
+
 func TestKillNoSession(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
@@ -196,7 +264,6 @@ func TestStartRunnigSession(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
 	s.Start(test_user, test_session, "pulsar")
-	time.Sleep(time.Second)
 	result := s.Start(test_user, test_session, "blinker")
 	expected := "session " + test_session + " is already running"
 	assert(result, expected, t)
@@ -217,19 +284,19 @@ func TestStartBadConfig(t *testing.T) {
 	result := s.Start(test_user, test_session, config)
 	expected := "the configuration you specified does not exist"
 	assert(result, expected, t)
+	result = s.Start(test_user, test_session, "../server.go")
+	assert(result, "invalid configuration name ../server.go", t)
 }
 
 func TestResumeProper(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
 	s.Start(test_user, test_session, "pulsar")
-	time.Sleep(time.Second)
 	s.Stop(test_user, test_session)
 	result := s.Resume(test_user, test_session)
 	expected := "successfully resumed session " + test_session
 	assert(result, expected, t)
-	time.Sleep(time.Second)
-	if !s.sessions[0].IsRunning {
+	if !s.sessions[0].IsRunning() {
 		t.Fatal("expected session to be running, it isn't")
 	}
 }
@@ -238,7 +305,6 @@ func TestResumeRunningSession(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
 	s.Start(test_user, test_session, "pulsar")
-	time.Sleep(time.Second)
 	result := s.Start(test_user, test_session, "blinker")
 	expected := "session " + test_session + " is already running"
 	assert(result, expected, t)
@@ -248,7 +314,6 @@ func TestStopProper(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
 	s.Start(test_user, test_session, "pulsar")
-	time.Sleep(time.Second)
 	result := s.Stop(test_user, test_session)
 	expected := "session " + test_session + " successfully stopped"
 	assert(result, expected, t)
@@ -258,7 +323,6 @@ func TestStopStopped(t *testing.T) {
 	t.Parallel()
 	s := getTestServer()
 	s.Start(test_user, test_session, "pulsar")
-	time.Sleep(time.Second)
 	s.Stop(test_user, test_session)
 	result := s.Stop(test_user, test_session)
 	expected := "session " + test_session + " is already stopped"
@@ -272,3 +336,800 @@ func TestListProper(t *testing.T) {
 	expectedLines := 12
 	assert(strconv.Itoa(result), strconv.Itoa(expectedLines), t)
 }
+
+func TestStartRLEConfig(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	for _, config := range []string{"replicator", "replicator_oscillator", "wtf"} {
+		if _, err := life.NewLife(config); err != nil {
+			t.Fatalf("failed to load %s: %s", config, err)
+		}
+	}
+	result := s.Start(test_user, test_session, "replicator")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+}
+
+func TestStartWithOptions(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "glider", "margin=2")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+
+	result = s.Start(test_user, "test_session1", "rpentomino", "margin")
+	assert(result, "malformed option margin", t)
+	result = s.Start(test_user, "test_session1", "rpentomino", "size=3")
+	assert(result, "unknown option size", t)
+	result = s.Start(test_user, "test_session1", "glider", "margin=1000000000000")
+	assert(result, "the margin must not exceed 4096", t)
+}
+
+func TestStartWithRule(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "pulsar", "rule=highlife")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+	if !strings.HasPrefix(s.Watch(test_user, test_session), "rule B36/S23, ") {
+		t.Fatal("expected watch to show the rule of the session")
+	}
+
+	result = s.Start(test_user, "test_session1", "pulsar", "rule=B9")
+	assert(result, "invalid rule B9", t)
+}
+
+func TestStartWithTopology(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "glider", "topology=torus")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+	if !strings.HasPrefix(s.Watch(test_user, test_session), "rule B3/S23, topology torus\n") {
+		t.Fatal("expected watch to show the topology of the session")
+	}
+
+	result = s.Start(test_user, "test_session1", "glider", "topology=sphere")
+	assert(result, "unknown topology sphere", t)
+}
+
+func TestWatchUnbounded(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "glider", "engine=sparse")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+
+	region := s.Watch(test_user, test_session, "0", "0", "2", "2")
+	if !strings.HasSuffix(region, "\n      \n      \n") {
+		t.Fatalf("expected an empty 2x2 region, got:\n%s", region)
+	}
+	result = s.Watch(test_user, test_session, "0", "0", "2")
+	assert(result, "a viewport is described by x, y, rows and columns", t)
+	result = s.Watch(test_user, test_session, "0", "0", "0", "2")
+	assert(result, "the viewport must have positive dimensions", t)
+
+	result = s.Start(test_user, "test_session1", "glider", "engine=sparse", "topology=torus")
+	assert(result, "the sparse engine does not support the torus topology", t)
+}
+
+func TestStartHashLife(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "rpentomino", "engine=hashlife", "jump=10")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+	header := "rule B3/S23, topology unbounded, 1024 generations per step\n"
+	if !strings.HasPrefix(s.Watch(test_user, test_session), header) {
+		t.Fatal("expected watch to show the number of generations per step")
+	}
+
+	result = s.Start(test_user, "test_session1", "rpentomino", "jump=10")
+	assert(result, "the dense engine does not support jumping", t)
+}
+
+func TestStartUnknownEngine(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "pulsar", "engine=quantum")
+	expected := "unknown engine quantum, available engines are dense, hashlife, packed, sparse"
+	assert(result, expected, t)
+
+	s.Start(test_user, test_session, "pulsar", "engine=packed")
+	if !strings.Contains(s.List(), "engine packed") {
+		t.Fatal("expected list to show the engine of the session")
+	}
+}
+
+func TestStartAutoStop(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "beehive", "autostop=true")
+	expected := "successfully started session " + test_session
+	assert(result, expected, t)
+
+	clock := s.clock.(*session.FakeClock)
+	clock.BlockUntil(1)
+	clock.Advance(session.DefaultInterval)
+	s.sessions[0].Wait()
+	if s.sessions[0].IsRunning() {
+		t.Fatal("expected the session to stop by itself")
+	}
+	listing := s.List()
+	if !strings.Contains(listing, "still life since generation 0, stopped automatically") {
+		t.Fatalf("expected list to show why the session stopped, got:\n%s", listing)
+	}
+	if !strings.Contains(s.Watch(test_user, test_session), "\nstill life since generation 0\n") {
+		t.Fatal("expected watch to show the status of the game")
+	}
+
+	result = s.Start(test_user, "test_session1", "beehive", "autostop=maybe")
+	assert(result, "invalid autostop maybe", t)
+}
+
+func TestStats(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Stats(test_user, test_session)
+	assert(result, "the session "+test_session+" has not been started", t)
+
+	s.Start(test_user, test_session, "beehive")
+	tick(s, 1)
+	s.Stop(test_user, test_session)
+	result = s.Stats(test_user, test_session)
+	if !strings.Contains(result, ", population 6 (+0 -0), bounding box ") {
+		t.Fatalf("unexpected stats:\n%s", result)
+	}
+	if !strings.Contains(result, "\npopulation history: 6 6") {
+		t.Fatalf("expected the population history, got:\n%s", result)
+	}
+	if !strings.Contains(s.Watch(test_user, test_session), "\ngeneration ") {
+		t.Fatal("expected watch to show the statistics of the game")
+	}
+	assert(s.Stats(test_user, "none"), "no session with the name none found", t)
+}
+
+func TestParseSpeed(t *testing.T) {
+	cases := map[string]time.Duration{
+		"max":   0,
+		"10":    100 * time.Millisecond,
+		"0.5":   2 * time.Second,
+		"250ms": 250 * time.Millisecond,
+	}
+	for speed, expected := range cases {
+		interval, err := session.ParseSpeed(speed)
+		if err != nil || interval != expected {
+			t.Fatalf("expected %s to mean %s, got %s (%v)", speed, expected, interval, err)
+		}
+	}
+	for _, speed := range []string{"0", "-3", "0s", "fast", "+Inf"} {
+		if _, err := session.ParseSpeed(speed); err == nil {
+			t.Fatalf("expected an error for speed %s", speed)
+		}
+	}
+}
+
+func TestSpeed(t *testing.T) {
+	t.Parallel()
+	s := NewServerWithClock(1, getTestClock())
+	s.Register(test_user, test_password)
+	s.Add(test_user, test_session)
+	result := s.Start(test_user, test_session, "pulsar", "speed=1h")
+	assert(result, "successfully started session "+test_session, t)
+	if !strings.Contains(s.List(), ", every 1h0m0s, ") {
+		t.Fatal("expected list to show the speed of the session")
+	}
+
+	result = s.Speed(test_user, test_session, "max")
+	assert(result, "session "+test_session+" now advances as fast as possible", t)
+	// the clock never moves, only running as fast as possible advances the game
+	for snapshot(s.sessions[0]).Generation() < 10 {
+		runtime.Gosched()
+	}
+	s.Stop(test_user, test_session)
+
+	assert(s.Speed("other", test_session, "10"), user.NotAuthorized("other"), t)
+	assert(s.Speed(test_user, test_session, "slow"), "invalid speed slow", t)
+	assert(s.Start(test_user, test_session, "pulsar", "speed=0"),
+		"the speed must be positive and finite", t)
+}
+
+func TestStep(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Step(test_user, test_session)
+	assert(result, "the session "+test_session+" has not been started", t)
+
+	s.Start(test_user, test_session, "blinker", "speed=1h")
+	result = s.Step(test_user, test_session)
+	assert(result, "session "+test_session+" is running, stop it first", t)
+	s.Stop(test_user, test_session)
+
+	first := s.Step(test_user, test_session)
+	if !strings.Contains(first, "\ngeneration 1, population 9 (+6 -6), ") {
+		t.Fatalf("expected the session to advance one generation, got:\n%s", first)
+	}
+	third := s.Step(test_user, test_session, "2")
+	if !strings.Contains(third, "\ngeneration 3, ") {
+		t.Fatalf("expected the session to advance two generations, got:\n%s", third)
+	}
+	if strings.SplitN(first, "\n", 4)[3] != strings.SplitN(third, "\n", 4)[3] {
+		t.Fatal("expected the blinkers to be back in the same phase")
+	}
+
+	assert(s.Step(test_user, test_session, "0"), "the number of steps must be between 1 and 10000", t)
+	assert(s.Step(test_user, test_session, "1000000000"),
+		"the number of steps must be between 1 and 10000", t)
+	assert(s.Step(test_user, test_session, "1", "2"), "step takes at most one number of steps", t)
+	assert(s.Step("other", test_session), user.NotAuthorized("other"), t)
+}
+
+func TestReset(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Reset(test_user, test_session)
+	assert(result, "the session "+test_session+" has not been started", t)
+
+	s.Start(test_user, test_session, "blinker", "speed=max")
+	result = s.Reset(test_user, test_session)
+	assert(result, "session "+test_session+" successfully reset", t)
+	if s.sessions[0].IsRunning() || snapshot(s.sessions[0]).Generation() != 0 {
+		t.Fatal("expected the session to be stopped at generation 0")
+	}
+
+	result = s.Reset(test_user, test_session, "run")
+	assert(result, "session "+test_session+" successfully reset and restarted", t)
+	if !s.sessions[0].IsRunning() {
+		t.Fatal("expected the session to be running")
+	}
+	s.Stop(test_user, test_session)
+	assert(s.Reset(test_user, test_session, "now"), "reset takes only the optional argument run", t)
+	assert(s.Reset("other", test_session), user.NotAuthorized("other"), t)
+}
+
+func TestRewindAndSeek(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	s.Start(test_user, test_session, "blinker", "speed=1h", "history=2")
+	s.Stop(test_user, test_session)
+	first := s.Step(test_user, test_session)
+	s.Step(test_user, test_session, "2")
+
+	result := s.Rewind(test_user, test_session, "2")
+	if !strings.Contains(result, "\ngeneration 1, ") || result != first {
+		t.Fatalf("expected the session to be back at generation 1, got:\n%s", result)
+	}
+	assert(s.Rewind(test_user, test_session, "1"), "the game can only be rewound by up to 0 steps", t)
+	result = s.Seek(test_user, test_session, "5")
+	if !strings.Contains(result, "\ngeneration 5, ") {
+		t.Fatalf("expected the session to be at generation 5, got:\n%s", result)
+	}
+	assert(s.Seek(test_user, test_session, "2"),
+		"generation 2 is no longer in the history, the oldest is 3", t)
+	assert(s.Seek(test_user, test_session, "soon"), "invalid generation soon", t)
+
+	s.Start(test_user, "test_session1", "blinker", "history=0")
+	s.Stop(test_user, "test_session1")
+	assert(s.Rewind(test_user, "test_session1", "1"), "the game keeps no history", t)
+	assert(s.Start(test_user, "test_session2", "blinker", "history=-1"),
+		"the history depth must not be negative", t)
+}
+
+func TestFork(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	s.Register("other", test_password)
+	result := s.Fork("other", test_session, "forked")
+	assert(result, "the session "+test_session+" has not been started", t)
+
+	s.Start(test_user, test_session, "blinker", "speed=1h")
+	s.Stop(test_user, test_session)
+	s.Step(test_user, test_session, "3")
+	result = s.Fork("other", test_session, "forked")
+	assert(result, "successfully forked session "+test_session+" into forked", t)
+	if !strings.Contains(s.List(), "forked from "+test_session+" at generation 3") {
+		t.Fatalf("expected list to show the lineage of the fork, got:\n%s", s.List())
+	}
+	forked := s.Step("other", "forked")
+	original := s.Step(test_user, test_session)
+	if forked != original {
+		t.Fatal("expected the fork to evolve like the original session")
+	}
+	assert(s.Step(test_user, "forked"), user.NotAuthorized(test_user), t)
+
+	s.Fork("other", test_session, "fromStart", "start")
+	if generation := snapshot(s.sessions[len(s.sessions)-1]).Generation(); generation != 0 {
+		t.Fatalf("expected the fork to start from generation 0, got %d", generation)
+	}
+	assert(s.Fork("other", test_session, "forked"), "session with the name forked already exists", t)
+	assert(s.Fork("other", test_session, "again", "now"), "fork takes only the optional argument start", t)
+	assert(s.Fork("other", "none", "again"), session.NoSession("none"), t)
+
+	s.Start("other", "forked", "glider", "speed=1h")
+	if strings.Contains(s.findSession("forked").GetStringRepresentation(), "forked from") {
+		t.Fatal("expected a fork started with a new config to lose its lineage")
+	}
+}
+
+func TestUntil(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "blinker", "speed=max", "until=generation:10")
+	assert(result, "successfully started session "+test_session, t)
+	s.sessions[0].Wait()
+	if s.sessions[0].IsRunning() || snapshot(s.sessions[0]).Generation() != 10 {
+		t.Fatal("expected the session to pause at generation 10")
+	}
+	if !strings.Contains(s.List(), ", paused on reaching generation 10") {
+		t.Fatalf("expected list to show why the session paused, got:\n%s", s.List())
+	}
+
+	result = s.Resume(test_user, test_session, "until=population-below:10", "until=generation:20")
+	assert(result, "successfully resumed session "+test_session, t)
+	s.sessions[0].Wait()
+	if !strings.Contains(s.List(), ", paused on population below 10") {
+		t.Fatalf("expected the first condition met to pause the session, got:\n%s", s.List())
+	}
+
+	assert(s.Resume(test_user, test_session, "until=forever"), "unknown condition forever", t)
+	assert(s.Resume(test_user, test_session, "until=generation"), "invalid condition generation", t)
+	assert(s.Resume(test_user, test_session, "speed=max"), "resume takes only until=<condition> options", t)
+	assert(s.Start(test_user, "test_session1", "blinker", "until=extinct:3"),
+		"invalid condition extinct:3", t)
+}
+
+func TestEditCells(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	s.Start(test_user, test_session, "blinker", "speed=1h")
+	assert(s.Clear(test_user, test_session), "session "+test_session+" is running, stop it first", t)
+	s.Stop(test_user, test_session)
+
+	assert(s.Clear(test_user, test_session), "session "+test_session+" successfully cleared", t)
+	result := s.Set(test_user, test_session, "0", "0", "alive")
+	if !strings.Contains(result, ", population 1 ") {
+		t.Fatalf("expected a live cell, got:\n%s", result)
+	}
+	result = s.Toggle(test_user, test_session, "0", "0")
+	if !strings.Contains(result, ", population 0 ") {
+		t.Fatalf("expected no live cells, got:\n%s", result)
+	}
+	assert(s.Set(test_user, test_session, "0", "0", "undead"), "the state of a cell is either alive or dead", t)
+	assert(s.Toggle(test_user, test_session, "7", "0"), "cell (7, 0) is outside the board", t)
+	assert(s.Toggle(test_user, test_session, "a", "0"), "invalid cell a 0", t)
+	assert(s.Clear("other", test_session), user.NotAuthorized("other"), t)
+
+	execResult, err := executor.Execute(s, "fill-random "+test_user+" "+test_session+" 0.5 42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := execResult[0].Interface().(string)
+	second := s.FillRandom(test_user, test_session, "0.5", "42")
+	if first != second || strings.Contains(first, ", population 0 ") {
+		t.Fatalf("expected the same random cells for the same seed, got:\n%s\n%s", first, second)
+	}
+	assert(s.FillRandom(test_user, test_session, "2"), "the density must be between 0 and 1", t)
+	assert(s.FillRandom(test_user, test_session, "0.5", "x"), "invalid seed x", t)
+}
+
+func TestPlace(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	s.Start(test_user, test_session, "blinker", "speed=1h")
+	result := s.Place(test_user, test_session, "glider", "0", "0", "90", "h")
+	if !strings.Contains(result, ", population 14 ") {
+		t.Fatalf("expected a glider to be added to the blinkers, got:\n%s", result)
+	}
+	if !s.sessions[0].IsRunning() {
+		t.Fatal("expected the session to keep running")
+	}
+	assert(s.Place(test_user, test_session, "glider", "5", "21"),
+		"the 3x3 pattern does not fit on the board at (5, 21)", t)
+	assert(s.Place(test_user, test_session, "glider", "0", "0", "45"),
+		"invalid rotation 45, expected a multiple of 90 degrees", t)
+	assert(s.Place(test_user, test_session, "glider", "0", "0", "90", "d"),
+		"invalid flip d, expected none, h or v", t)
+	assert(s.Place(test_user, test_session, "nothing", "0", "0"),
+		"the configuration you specified does not exist", t)
+	assert(s.Place(test_user, test_session, "../../etc/passwd", "0", "0"),
+		"invalid configuration name ../../etc/passwd", t)
+	assert(s.Place("other", test_session, "glider", "0", "0"), user.NotAuthorized("other"), t)
+}
+
+func TestTransform(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	result := s.Start(test_user, test_session, "glider", "speed=1h", "transform=rotate:90", "transform=pad:2")
+	assert(result, "successfully started session "+test_session, t)
+	s.Stop(test_user, test_session)
+	before := snapshot(s.sessions[0]).Printable()
+	if rows := strings.Count(before, "\n"); rows != 23 {
+		t.Fatalf("expected 23 rows, got %d", rows)
+	}
+
+	result = s.Transform(test_user, test_session, "crop", "transpose")
+	if !strings.HasSuffix(result, "\n *  *  * \n       * \n    *    \n") {
+		t.Fatalf("expected the cropped and transposed glider, got:\n%s", result)
+	}
+	assert(s.Transform(test_user, test_session), "transform takes at least one transform", t)
+	assert(s.Transform(test_user, test_session, "skew:1"), "unknown transform skew", t)
+	assert(s.Start(test_user, "test_session1", "glider", "transform=pad:-1"),
+		"the padding must not be negative", t)
+}
+
+func TestStartSoup(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	config := "random:16x16:0.3:12345:D8"
+	result := s.Start(test_user, test_session, config, "speed=1h")
+	assert(result, "successfully started session "+test_session, t)
+	result = s.Start(test_user, "test_session1", config, "speed=1h")
+	assert(result, "successfully started session test_session1", t)
+	if s.Watch(test_user, test_session) != s.Watch(test_user, "test_session1") {
+		t.Fatal("expected soups with the same seed to be equal")
+	}
+	if !strings.Contains(s.List(), ", config "+config+", ") {
+		t.Fatalf("expected list to show the config of the session, got:\n%s", s.List())
+	}
+	assert(s.Start(test_user, "test_session2", "random:16x8:0.3:1:C4"),
+		"the C4 symmetry requires a square soup", t)
+}
+
+func TestSessionConcurrentRunAndStop(t *testing.T) {
+	t.Parallel()
+	s := getTestSession(getTestClock())
+	s.SetInterval(0)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch (i + j) % 4 {
+				case 0:
+					s.Run()
+				case 1:
+					s.Stop()
+				case 2:
+					s.View(func(game *life.Life) {
+						game.Printable()
+					})
+				case 3:
+					s.SetInterval(time.Duration(j%2) * time.Millisecond)
+					s.GetStringRepresentation()
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	s.Stop()
+	if s.IsRunning() {
+		t.Fatal("expected the session to be stopped")
+	}
+}
+
+func TestConcurrentRequests(t *testing.T) {
+	t.Parallel()
+	s := NewServer()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			username := fmt.Sprintf("user%d", i)
+			name := fmt.Sprintf("session%d", i)
+			other := fmt.Sprintf("session%d", (i+1)%8)
+			expect(s.Register(username, test_password), "registered user "+username, t)
+			expect(s.Add(username, name), "successfully created session "+name, t)
+			expect(s.Start(username, name, "glider", "speed=max", "topology=torus"),
+				"successfully started session "+name, t)
+			for j := 0; j < 20; j++ {
+				s.Watch(username, other)
+				s.Stats(username, other)
+				s.List()
+				s.Fork(username, other, fmt.Sprintf("fork%d-%d", i, j))
+				s.Place(username, name, "blinker", "0", "0")
+				s.Speed(username, name, strconv.Itoa(1000*(j+1)))
+			}
+			s.Stop(username, name)
+			s.Step(username, name, "5")
+			s.Toggle(username, name, "1", "1")
+			s.Reset(username, name, "run")
+			expect(s.Kill(username, name), "session "+name+" successfully killed", t)
+		}(i)
+	}
+	wg.Wait()
+	for _, current := range s.sessions {
+		if current.IsRunning() {
+			t.Fatalf("expected forks to be stopped, %s is running", current.Name)
+		}
+	}
+	for i := 0; i < 8; i++ {
+		if name := fmt.Sprintf("session%d", i); s.findSession(name) != nil {
+			t.Fatalf("expected %s to be killed", name)
+		}
+	}
+}
+
+func TestKillWhileWatched(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("test_session%d", i)
+		s.Start(test_user, name, "pulsar", "speed=max")
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(2)
+		name := fmt.Sprintf("test_session%d", i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s.Watch("watcher", name)
+				s.List()
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			expect(s.Kill(test_user, name), "session "+name+" successfully killed", t)
+		}()
+	}
+	wg.Wait()
+	assert(s.List(), "\n0 total", t)
+}
+
+func TestFakeClock(t *testing.T) {
+	clock := getTestClock()
+	start := clock.Now()
+	early := clock.NewTimer(time.Second)
+	late := clock.NewTimer(time.Minute)
+	stopped := clock.NewTimer(time.Second)
+	if !stopped.Stop() {
+		t.Fatal("expected a pending timer to stop")
+	}
+	clock.BlockUntil(2)
+	clock.Advance(2 * time.Second)
+	select {
+	case now := <-early.C():
+		if now != start.Add(2*time.Second) {
+			t.Fatalf("expected the timer to deliver the advanced time, got %s", now)
+		}
+	default:
+		t.Fatal("expected the due timer to fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("expected the timer to wait for its deadline")
+	case <-stopped.C():
+		t.Fatal("expected the stopped timer not to fire")
+	default:
+	}
+	if early.Stop() || !late.Stop() {
+		t.Fatal("expected only pending timers to stop")
+	}
+}
+
+func TestPersistence(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := storage.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServerWithClock(1, getTestClock())
+	if err := s.restore(store); err != nil {
+		t.Fatal(err)
+	}
+	s.Register(test_user, test_password)
+	s.Register("other", "secret")
+	for _, name := range []string{"running", "stopped", "killed", "idle", ".hidden"} {
+		s.Add(test_user, name)
+	}
+	s.Start(test_user, "running", "glider", "topology=torus", "speed=2", "until=generation:100")
+	s.Start(test_user, "stopped", "blinker", "rule=highlife", "history=4")
+	s.Stop(test_user, "stopped")
+	s.Step(test_user, "stopped", "3")
+	s.Toggle(test_user, "stopped", "0", "0")
+	s.Fork("other", "stopped", "forked")
+	s.Start(test_user, "killed", "pulsar")
+	s.Kill(test_user, "killed")
+	s.Stop(test_user, "running")
+	s.Step(test_user, "running", "5")
+	s.Resume(test_user, "running", "until=generation:100")
+
+	store, err = storage.Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := NewServerWithClock(1, getTestClock())
+	if err := restored.restore(store); err != nil {
+		t.Fatal(err)
+	}
+	assert(restored.Login("other", "secret"), "user other logged in", t)
+	assert(restored.Login(test_user, "wrong"), "invalid password for "+test_user, t)
+	assert(restored.Watch(test_user, "killed"), session.NoSession("killed"), t)
+	assert(restored.Watch(test_user, "idle"), "the session idle has not been started", t)
+	assert(restored.Watch(test_user, ".hidden"), "the session .hidden has not been started", t)
+	for _, name := range []string{"running", "stopped", "forked"} {
+		original, copy := s.findSession(name), restored.findSession(name)
+		if copy == nil {
+			t.Fatalf("expected session %s to be restored", name)
+		}
+		if original.IsRunning() != copy.IsRunning() {
+			t.Fatalf("expected session %s to be restored running as it was", name)
+		}
+		before, after := snapshot(original), snapshot(copy)
+		if before.Generation() != after.Generation() || before.Printable() != after.Printable() ||
+			before.Rule() != after.Rule() || before.Topology() != after.Topology() {
+			t.Fatalf("expected the game of session %s to be restored as it was", name)
+		}
+	}
+	listing := restored.List()
+	for _, expected := range []string{
+		"session running, created at ", ", config glider, rule B3/S23, topology torus, ",
+		", every 500ms, ", ", forked from stopped at generation 3", "\n5 total",
+	} {
+		if !strings.Contains(listing, expected) {
+			t.Fatalf("expected list to contain %q, got:\n%s", expected, listing)
+		}
+	}
+	// the history begins anew at the restored generation
+	assert(restored.Rewind(test_user, "stopped", "1"),
+		"the game can only be rewound by up to 0 steps", t)
+	restored.Step(test_user, "stopped")
+	if !strings.Contains(restored.Rewind(test_user, "stopped", "1"), "\ngeneration 3, ") {
+		t.Fatal("expected the restored session to keep recording its history")
+	}
+	s.Stop(test_user, "running")
+	restored.Stop(test_user, "running")
+}
+
+func TestCheckpoints(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, _ := storage.Open(dir)
+	if store.Crashed() {
+		t.Fatal("expected a new data directory not to report a crash")
+	}
+	s := NewServerWithClock(1, getTestClock())
+	s.checkpointEvery(5, 0)
+	s.restore(store)
+	s.Register(test_user, test_password)
+	s.Add(test_user, "paused")
+	s.Start(test_user, "paused", "blinker", "speed=max", "until=generation:7")
+	s.findSession("paused").Wait()
+	s.Add(test_user, "running")
+	s.Start(test_user, "running", "glider", "topology=torus")
+	tick(s, 12)
+
+	// the server crashes without closing its store
+	crashed, _ := storage.Open(dir)
+	if !crashed.Crashed() {
+		t.Fatal("expected the data directory to report the crash")
+	}
+	restored := NewServerWithClock(1, getTestClock())
+	if err := restored.restore(crashed); err != nil {
+		t.Fatal(err)
+	}
+	if game := snapshot(restored.findSession("running")); game.Generation() != 10 {
+		t.Fatalf("expected the session to resume from generation 10, got %d", game.Generation())
+	}
+	if game := snapshot(restored.findSession("paused")); game.Generation() != 7 {
+		t.Fatalf("expected the paused session to be saved at generation 7, got %d", game.Generation())
+	}
+	listing := restored.List()
+	if !strings.Contains(listing, "config glider, rule B3/S23, topology torus, engine dense, every 1s, evolving, generations after 10 lost in a crash") {
+		t.Fatalf("expected list to report the lost generations, got:\n%s", listing)
+	}
+	if strings.Count(listing, "lost in a crash") != 1 {
+		t.Fatalf("expected only the running session to have lost generations, got:\n%s", listing)
+	}
+	restored.Stop(test_user, "running")
+	s.Stop(test_user, "running")
+
+	// the latest version of a file is unreadable, the previous one is used
+	file := filepath.Join(dir, "sessions", "running.json")
+	if err := os.WriteFile(file, []byte("{\"Name\": \"runn"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := crashed.Close(); err != nil {
+		t.Fatal(err)
+	}
+	reopened, _ := storage.Open(dir)
+	if reopened.Crashed() {
+		t.Fatal("expected a closed data directory not to report a crash")
+	}
+	fallback := NewServerWithClock(1, getTestClock())
+	if err := fallback.restore(reopened); err != nil {
+		t.Fatal(err)
+	}
+	if game := snapshot(fallback.findSession("running")); game.Generation() != 10 {
+		t.Fatalf("expected the previous save at generation 10, got %d", game.Generation())
+	}
+	if fallback.findSession("running").IsRunning() {
+		t.Fatal("expected the session to be restored as it was last saved, stopped")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, _ := storage.Open(dir)
+	s := NewServerWithClock(1, getTestClock())
+	s.restore(store)
+	s.Register(test_user, test_password)
+	s.Add(test_user, "paused")
+	s.Start(test_user, "paused", "blinker")
+	s.Stop(test_user, "paused")
+	s.Add(test_user, "running")
+	s.Start(test_user, "running", "glider", "topology=torus")
+	tick(s, 3)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() {
+		served <- s.serve(listener)
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	responses := bufio.NewReader(client)
+	client.Write([]byte("login " + test_user + " " + test_password + "\000"))
+	response, _ := responses.ReadString('\000')
+	assert(response, "user "+test_user+" logged in\000", t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	listener.Close()
+	if err := <-served; err != nil {
+		t.Fatalf("expected serving to end once the listener is closed, got %v", err)
+	}
+	notice, _ := responses.ReadString('\000')
+	assert(notice, shuttingDown+"\000", t)
+	if _, err := responses.ReadString('\000'); err == nil {
+		t.Fatal("expected the client to be disconnected")
+	}
+	if s.findSession("running").IsRunning() {
+		t.Fatal("expected the running session to be stopped")
+	}
+
+	reopened, _ := storage.Open(dir)
+	if reopened.Crashed() {
+		t.Fatal("expected the store to be closed on shutdown")
+	}
+	restored := NewServerWithClock(1, getTestClock())
+	if err := restored.restore(reopened); err != nil {
+		t.Fatal(err)
+	}
+	running, paused := restored.findSession("running"), restored.findSession("paused")
+	if !running.IsRunning() || snapshot(running).Generation() != 3 {
+		t.Fatal("expected the running session to run again from where it stopped")
+	}
+	if paused.IsRunning() {
+		t.Fatal("expected the paused session to stay paused")
+	}
+	if strings.Contains(restored.List(), "lost in a crash") {
+		t.Fatal("expected no generations to be lost on shutdown")
+	}
+	restored.Stop(test_user, "running")
+}
+
+func TestExecuteOnlyCommands(t *testing.T) {
+	t.Parallel()
+	s := getTestServer()
+	for _, request := range []string{
+		"shutdown x", "serve x", "restore x", "checkpoint-every 1 2",
+		"assert-executable", "nonsense",
+	} {
+		assert(s.execute(request), "internal server error", t)
+	}
+	assert(s.execute("login "+test_user+" "+test_password), "user "+test_user+" logged in", t)
+}
@@ -0,0 +1,137 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// A Clock tells the time and schedules the generations of sessions. Sessions
+// use the SystemClock unless given another, e.g. a FakeClock in tests.
+type Clock interface {
+	// Returns the current time.
+	Now() time.Time
+	// Returns a timer which fires once `d` has passed.
+	NewTimer(d time.Duration) Timer
+}
+
+// A Timer delivers the time on its channel once it fires.
+type Timer interface {
+	// Returns the channel the time is delivered on.
+	C() <-chan time.Time
+	// Prevents the timer from firing. Reports false if it already has.
+	Stop() bool
+}
+
+// The clock of the machine, backed by package time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+// A FakeClock only moves forward when advanced, firing the timers which come
+// due. It lets tests drive sessions generation by generation.
+type FakeClock struct {
+	mutex   sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	channel  chan time.Time
+}
+
+// Constructs a FakeClock showing the time `now`.
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.changed = sync.NewCond(&c.mutex)
+	return c
+}
+
+// Returns the time the clock shows.
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// Returns a timer which fires once the clock has been advanced by `d`.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t := &fakeTimer{clock: c, deadline: c.now.Add(d), channel: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.channel <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.changed.Broadcast()
+	return t
+}
+
+// Moves the clock forward by `d`, firing the timers which come due.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+		} else {
+			t.channel <- c.now
+		}
+	}
+	clear(c.timers[len(pending):])
+	c.timers = pending
+	c.changed.Broadcast()
+}
+
+// Blocks until at least `n` timers are waiting to fire, e.g. until `n` running
+// sessions wait for their next generation.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.timers) < n {
+		c.changed.Wait()
+	}
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.channel
+}
+
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for idx, pending := range c.timers {
+		if pending == t {
+			c.timers = append(c.timers[:idx], c.timers[idx+1:]...)
+			c.changed.Broadcast()
+			return true
+		}
+	}
+	return false
+}
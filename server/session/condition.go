@@ -0,0 +1,118 @@
+package session
+
+import (
+	"LaaS/life"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+type conditionKind uint8
+
+const (
+	untilGeneration conditionKind = iota
+	untilPopulationAbove
+	untilPopulationBelow
+	untilExtinct
+	untilStable
+)
+
+var conditionKinds = map[string]conditionKind{
+	"generation":       untilGeneration,
+	"population-above": untilPopulationAbove,
+	"population-below": untilPopulationBelow,
+	"extinct":          untilExtinct,
+	"stable":           untilStable,
+}
+
+// A Condition upon which a running session pauses itself.
+type Condition struct {
+	kind  conditionKind
+	value uint64
+}
+
+// Parses a stopping condition, one of:
+//   - generation:<n>: the game reaches generation n
+//   - population-above:<n>: more than n cells are alive
+//   - population-below:<n>: fewer than n cells are alive
+//   - extinct: no cells are alive
+//   - stable: the game dies out or becomes periodic
+func ParseCondition(condition string) (Condition, error) {
+	name, value, hasValue := strings.Cut(condition, ":")
+	kind, found := conditionKinds[name]
+	if !found {
+		return Condition{}, errors.New("unknown condition " + condition)
+	}
+	if hasValue != kind.takesValue() {
+		return Condition{}, errors.New("invalid condition " + condition)
+	}
+	var parsed uint64
+	if hasValue {
+		var err error
+		parsed, err = strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return Condition{}, errors.New("invalid condition " + condition)
+		}
+	}
+	return Condition{kind, parsed}, nil
+}
+
+// Reports whether the condition holds for `game`.
+func (c Condition) Met(game *life.Life) bool {
+	switch c.kind {
+	case untilGeneration:
+		return game.Generation() >= c.value
+	case untilPopulationAbove:
+		return uint64(game.Population()) > c.value
+	case untilPopulationBelow:
+		return uint64(game.Population()) < c.value
+	case untilExtinct:
+		return game.Status().Behaviour == life.Extinct
+	}
+	return game.Status().Settled()
+}
+
+// Returns the reason a session paused on meeting the condition.
+func (c Condition) String() string {
+	value := strconv.FormatUint(c.value, 10)
+	switch c.kind {
+	case untilGeneration:
+		return "paused on reaching generation " + value
+	case untilPopulationAbove:
+		return "paused on population above " + value
+	case untilPopulationBelow:
+		return "paused on population below " + value
+	case untilExtinct:
+		return "paused on extinction"
+	}
+	return "paused on stabilization"
+}
+
+func (kind conditionKind) takesValue() bool {
+	return kind != untilExtinct && kind != untilStable
+}
+
+// Implement the encoding.TextMarshaler interface, producing the notation
+// accepted by ParseCondition.
+func (c Condition) MarshalText() ([]byte, error) {
+	for name, kind := range conditionKinds {
+		if kind != c.kind {
+			continue
+		}
+		if kind.takesValue() {
+			name += ":" + strconv.FormatUint(c.value, 10)
+		}
+		return []byte(name), nil
+	}
+	return nil, errors.New("unknown condition kind " + strconv.Itoa(int(c.kind)))
+}
+
+// Implement the encoding.TextUnmarshaler interface, see ParseCondition.
+func (c *Condition) UnmarshalText(text []byte) error {
+	parsed, err := ParseCondition(string(text))
+	if err != nil {
+		return err
+	}
+	*c = parsed
+	return nil
+}
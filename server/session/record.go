@@ -0,0 +1,90 @@
+package session
+
+import (
+	"LaaS/life"
+	"LaaS/server/user"
+	"errors"
+	"time"
+)
+
+// A Record is the persistent form of a session: its metadata, settings and
+// game, along with whether it was running.
+type Record struct {
+	Name       string
+	Owner      string
+	Created    time.Time
+	Config     string       `json:",omitempty"`
+	Game       *life.Record `json:",omitempty"`
+	Running    bool
+	Interval   time.Duration
+	AutoStop   bool        `json:",omitempty"`
+	Until      []Condition `json:",omitempty"`
+	StopReason string      `json:",omitempty"`
+	ForkedFrom string      `json:",omitempty"`
+	ForkedAt   uint64      `json:",omitempty"`
+}
+
+// Returns the persistent form of the session. Running sessions are recorded
+// between two generations.
+func (s *Session) Record() Record {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	record := Record{
+		Name:       s.Name,
+		Owner:      s.owner.Name,
+		Created:    s.created,
+		Config:     s.config,
+		Running:    s.running,
+		Interval:   s.interval,
+		AutoStop:   s.autoStop,
+		Until:      s.until,
+		StopReason: s.stopReason,
+		ForkedFrom: s.forkedFrom,
+		ForkedAt:   s.forkedAt,
+	}
+	if s.game != nil {
+		game := s.game.Record()
+		record.Game = &game
+	}
+	return record
+}
+
+// Constructs the stopped session described by `record`, owned by `owner` and
+// running by `clock`. Sessions which were running are to be run again by the
+// caller.
+// Fails if the owner does not match the record or the game cannot be
+// restored.
+func Restore(record Record, owner *user.User, clock Clock) (*Session, error) {
+	if owner == nil || owner.Name != record.Owner {
+		return nil, errors.New("the owner of session " + record.Name + " does not exist")
+	}
+	s := NewSessionWithClock(record.Name, owner, clock)
+	s.created = record.Created
+	s.config = record.Config
+	s.interval = record.Interval
+	s.autoStop = record.AutoStop
+	s.until = record.Until
+	s.stopReason = record.StopReason
+	s.forkedFrom = record.ForkedFrom
+	s.forkedAt = record.ForkedAt
+	if record.Game != nil {
+		game, err := life.Restore(*record.Game)
+		if err != nil {
+			return nil, errors.New("the game of session " + record.Name +
+				" cannot be restored: " + err.Error())
+		}
+		s.game = game
+	}
+	return s, nil
+}
+
+// Records that the generations of the game after the current one were lost
+// in a crash, until the game is reset or replaced.
+func (s *Session) MarkRecovered() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.game != nil {
+		s.recovered = true
+		s.lostAfter = s.game.Generation()
+	}
+}
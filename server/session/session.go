@@ -5,61 +5,494 @@ package session
 import (
 	"LaaS/life"
 	"LaaS/server/user"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 )
 
 const timeFormat = "Mon Jan 2 2006 15:04"
 
-// A session is represented by its name, owner, creation time and the current
-// state of the game. It also contains a channel used to signal the game to
-// stop and a flag indicating if the game is currently running or not.
+// A session is represented by its name, owner, creation time, the
+// configuration its game was started from and the current state of the game.
+// It also contains a channel used to signal the game to stop and a flag
+// indicating if the game is currently running or not.
+// If autoStop is set the session stops by itself once the game dies out or
+// becomes periodic, and it pauses itself once any of the conditions it is
+// running until is met, recording why in stopReason.
+// The game advances one generation every interval as told by the clock of
+// the session, or as fast as the Budget shared with the other sessions allows
+// if the interval is zero.
+// Sessions forked from another session record its name and the generation
+// of the game when it was forked, until their game is replaced. Running sessions save themselves according
+// to their Checkpoints, and sessions recovered after a crash record the
+// generation the game was recovered at. Sessions removed from the server
+// are closed and never run again.
+//
+// Sessions are safe for concurrent use: the game and the state of the session
+// are guarded by a mutex, which the goroutine running the game holds while
+// computing a generation. The name, owner, creation time, budget and
+// checkpoints of a session do not change once it is shared.
 type Session struct {
-	owner     *user.User
-	Name      string
-	created   time.Time
-	CurrState *life.Life
-	stopper   chan struct{}
-	IsRunning bool
+	owner       *user.User
+	Name        string
+	created     time.Time
+	clock       Clock
+	Budget      *Budget
+	Checkpoints *Checkpoints
+	mutex       sync.Mutex
+	config      string
+	game        *life.Life
+	stopper     chan struct{}
+	done        chan struct{}
+	wake        chan struct{}
+	interval    time.Duration
+	running     bool
+	autoStop    bool
+	until       []Condition
+	stopReason  string
+	forkedFrom  string
+	forkedAt    uint64
+	recovered   bool
+	lostAfter   uint64
+	closed      bool
 }
 
-// Constructs a new session.
+// Checkpoints decide when running sessions save themselves: once `Interval`
+// has passed or the game has advanced by `Generations` generations since
+// they were last saved, whichever comes first, and once they stop by
+// themselves. Zero disables either limit.
+type Checkpoints struct {
+	Generations uint64
+	Interval    time.Duration
+	// Saves `s`, called by the goroutine running it without holding its
+	// mutex.
+	Save func(s *Session)
+}
+
+// Reports whether a checkpoint is due `advanced` generations and `elapsed`
+// time after the last one.
+func (c *Checkpoints) due(advanced uint64, elapsed time.Duration) bool {
+	return c != nil && (c.Generations > 0 && advanced >= c.Generations ||
+		c.Interval > 0 && elapsed >= c.Interval)
+}
+
+// Saves `s`, if checkpoints are enabled.
+func (c *Checkpoints) save(s *Session) {
+	if c != nil && c.Save != nil {
+		c.Save(s)
+	}
+}
+
+// The settings a session is started with.
+type Settings struct {
+	// Stop once the game dies out or becomes periodic.
+	AutoStop bool
+	// The time between generations, zero to run as fast as possible.
+	Interval time.Duration
+	// Pause once any of the conditions is met.
+	Until []Condition
+}
+
+// Constructs a new session which runs by the SystemClock.
 func NewSession(name string, owner *user.User) *Session {
+	return NewSessionWithClock(name, owner, SystemClock)
+}
+
+// Constructs a new session which runs by `clock`.
+func NewSessionWithClock(name string, owner *user.User, clock Clock) *Session {
 	s := new(Session)
 	s.Name = name
-	s.created = time.Now()
+	s.clock = clock
+	s.created = clock.Now()
 	s.owner = owner
+	s.interval = DefaultInterval
+	s.wake = make(chan struct{}, 1)
 	return s
 }
 
+func (s *Session) notStarted() error {
+	return errors.New("the session " + s.Name + " has not been started")
+}
+
+func (s *Session) alreadyRunning() error {
+	return errors.New("session " + s.Name + " is already running")
+}
+
+func (s *Session) isClosed() error {
+	return errors.New(NoSession(s.Name))
+}
+
+// Returns the time between generations, zero if the session runs as fast as
+// possible.
+func (s *Session) Interval() time.Duration {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.interval
+}
+
+// Sets the time between generations, zero to run as fast as possible. Takes
+// effect immediately, even if the session is waiting for its next generation.
+func (s *Session) SetInterval(interval time.Duration) {
+	s.mutex.Lock()
+	s.interval = interval
+	s.mutex.Unlock()
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Reports whether the game is currently running.
+func (s *Session) IsRunning() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.running
+}
+
 // Returns a string describing the session (human readable).
 func (s *Session) GetStringRepresentation() string {
-	return "session " + s.Name + ", created at " + s.created.Format(timeFormat)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	representation := "session " + s.Name + ", created at " + s.created.Format(timeFormat)
+	if s.game != nil {
+		representation += ", config " + s.config +
+			", rule " + s.game.Rule().String() +
+			", topology " + s.game.Topology().String() +
+			", engine " + s.game.EngineName() +
+			", " + FormatSpeed(s.interval) +
+			", " + s.game.Status().String()
+		if s.stopReason != "" {
+			representation += ", " + s.stopReason
+		}
+		if s.forkedFrom != "" {
+			representation += fmt.Sprintf(", forked from %s at generation %d",
+				s.forkedFrom, s.forkedAt)
+		}
+		if s.recovered {
+			representation += fmt.Sprintf(", generations after %d lost in a crash",
+				s.lostAfter)
+		}
+	}
+	return representation
+}
+
+// Replaces the game of a stopped session with `game`, started from `config`.
+// Fails if the session is running.
+func (s *Session) Load(config string, game *life.Life) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.running {
+		return s.alreadyRunning()
+	}
+	s.config, s.game = config, game
+	s.stopReason = ""
+	s.recovered = false
+	s.forkedFrom, s.forkedAt = "", 0
+	return nil
+}
+
+// Replaces the game of a stopped session with `game`, started from `config`,
+// and begins iterating its generations with `settings`.
+// Fails if the session is running or closed.
+func (s *Session) Start(config string, game *life.Life, settings Settings) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return s.isClosed()
+	}
+	if s.running {
+		return s.alreadyRunning()
+	}
+	s.config, s.game = config, game
+	s.autoStop, s.interval, s.until = settings.AutoStop, settings.Interval, settings.Until
+	s.recovered = false
+	s.forkedFrom, s.forkedAt = "", 0
+	s.run()
+	return nil
+}
+
+// Begins iterating the generations of the game.
+// Fails if the session is running, closed or had not been started.
+func (s *Session) Run() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return s.isClosed()
+	}
+	if s.game == nil {
+		return s.notStarted()
+	}
+	if s.running {
+		return s.alreadyRunning()
+	}
+	s.run()
+	return nil
+}
+
+// Begins iterating the generations of the game until any of the conditions
+// `until` is met, replacing the conditions the session was running until.
+// Fails if the session is running, closed or had not been started.
+func (s *Session) Resume(until []Condition) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return s.isClosed()
+	}
+	if s.game == nil {
+		return s.notStarted()
+	}
+	if s.running {
+		return s.alreadyRunning()
+	}
+	s.until = until
+	s.run()
+	return nil
+}
+
+// Starts the goroutine running the game. The mutex must be held.
+func (s *Session) run() {
+	s.running = true
+	s.stopReason = ""
+	s.stopper = make(chan struct{})
+	s.done = make(chan struct{})
+	go s.loop(s.stopper, s.done, s.game.Generation())
+}
+
+// Computes the generations of the game, starting at `generation`, until
+// signaled on `stopper` or until the session stops by itself, closing `done`
+// once it has stopped. Sessions which stop by themselves are saved before.
+func (s *Session) loop(stopper, done chan struct{}, generation uint64) {
+	defer close(done)
+	savedGeneration, savedAt := generation, s.clock.Now()
+	for {
+		if !s.wait(stopper) {
+			s.mutex.Lock()
+			s.running = false
+			s.mutex.Unlock()
+			return
+		}
+		s.Budget.acquire()
+		s.mutex.Lock()
+		s.game.NextGeneration()
+		reason := s.shouldStop()
+		if reason != "" {
+			s.stopReason = reason
+			s.running = false
+		}
+		generation, now := s.game.Generation(), s.clock.Now()
+		s.mutex.Unlock()
+		s.Budget.release()
+		if reason != "" {
+			s.Checkpoints.save(s)
+			return
+		}
+		if s.Checkpoints.due(generation-savedGeneration, now.Sub(savedAt)) {
+			s.Checkpoints.save(s)
+			savedGeneration, savedAt = generation, now
+		}
+	}
+}
+
+// Returns why the session should stop by itself, the empty string if it
+// should keep running. The mutex must be held.
+func (s *Session) shouldStop() string {
+	if s.autoStop && s.game.Status().Settled() {
+		return "stopped automatically"
+	}
+	for _, condition := range s.until {
+		if condition.Met(s.game) {
+			return condition.String()
+		}
+	}
+	return ""
 }
 
-// Begins iteration the generations of the game.
-// DO NOT call Run() on sessions whose state has not been initialized.
-func (s *Session) Run() {
-	go func() {
-		s.IsRunning = true
-		s.stopper = make(chan struct{})
-		for {
+// Waits for the time of the next generation. Reports false if the session was
+// signaled to stop in the meantime.
+func (s *Session) wait(stopper chan struct{}) bool {
+	for {
+		interval := s.Interval()
+		if interval == 0 {
 			select {
-			case <-s.stopper:
-				s.IsRunning = false
-				close(s.stopper)
-				return
+			case <-stopper:
+				return false
 			default:
-				time.Sleep(time.Second)
-				s.CurrState.NextGeneration()
+				return true
 			}
 		}
-	}()
+		timer := s.clock.NewTimer(interval)
+		select {
+		case <-stopper:
+			timer.Stop()
+			return false
+		case <-s.wake:
+			// the interval changed, start waiting anew
+			timer.Stop()
+		case <-timer.C():
+			return true
+		}
+	}
+}
+
+// Signals the session to stop executing the game and waits until it has.
+// Fails if the session is not running.
+func (s *Session) Stop() error {
+	s.mutex.Lock()
+	if !s.running {
+		s.mutex.Unlock()
+		return errors.New("session " + s.Name + " is already stopped")
+	}
+	stopper, done := s.stopper, s.done
+	s.mutex.Unlock()
+	select {
+	case stopper <- struct{}{}:
+		<-done
+	case <-done:
+		// the session stopped by itself in the meantime
+	}
+	return nil
+}
+
+// Closes the session, stopping it if it is running. Closed sessions can no
+// longer be started, run, resumed or restarted.
+func (s *Session) Close() {
+	s.mutex.Lock()
+	s.closed = true
+	s.mutex.Unlock()
+	s.Stop()
+}
+
+// Blocks until the session stops running, by itself or when signaled to.
+// Returns immediately if the session is not running.
+func (s *Session) Wait() {
+	s.mutex.Lock()
+	running, done := s.running, s.done
+	s.mutex.Unlock()
+	if running {
+		<-done
+	}
+}
+
+// Calls `view` with the game, which it must not modify or retain.
+// Fails if the session had not been started.
+func (s *Session) View(view func(game *life.Life)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.game == nil {
+		return s.notStarted()
+	}
+	view(s.game)
+	return nil
+}
+
+// Calls `update` with the game, which it may modify but must not retain,
+// whether the session is running or not.
+// Fails if the session had not been started or if `update` fails.
+func (s *Session) Update(update func(game *life.Life) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.game == nil {
+		return s.notStarted()
+	}
+	return update(s.game)
+}
+
+// Calls `edit` with the game of a stopped session, which it may modify but
+// must not retain.
+// Fails if the session had not been started, is running or if `edit` fails.
+func (s *Session) Edit(edit func(game *life.Life) error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.game == nil {
+		return s.notStarted()
+	}
+	if s.running {
+		return errors.New("session " + s.Name + " is running, stop it first")
+	}
+	return edit(s.game)
+}
+
+// Advances the game of a stopped session by `n` steps, each of as many
+// generations as the game advances by per step. The session is unlocked
+// between steps, so that it can be viewed in the meantime.
+// Fails if the session had not been started or is running.
+func (s *Session) Step(n int) error {
+	advance := func(game *life.Life) error {
+		game.NextGeneration()
+		return nil
+	}
+	for i := 0; i < n; i++ {
+		s.Budget.acquire()
+		err := s.Edit(advance)
+		s.Budget.release()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Moves the game of a stopped session to `generation`. Generations past its
+// history are computed like Step computes them.
+// Fails if the session had not been started, is running or if the game cannot
+// seek the generation.
+func (s *Session) Seek(generation uint64) error {
+	var steps int
+	err := s.Edit(func(game *life.Life) error {
+		var err error
+		steps, err = game.SeekHistory(generation)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return s.Step(steps)
+}
+
+// Restores the starting configuration of the game, stopping the session if
+// it is running and running it again afterwards if `restart` is set.
+// Fails if the session is closed or had not been started.
+func (s *Session) Reset(restart bool) error {
+	s.Stop()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return s.isClosed()
+	}
+	if s.game == nil {
+		return s.notStarted()
+	}
+	s.game.Reset()
+	s.stopReason = ""
+	s.recovered = false
+	if restart && !s.running {
+		s.run()
+	}
+	return nil
 }
 
-// Signals the session to stop executing the game.
-func (s *Session) Stop() {
-	s.stopper <- struct{}{}
+// Returns a new stopped session named `name` and owned by `owner` whose game
+// is a copy of the game of the session, or of its starting configuration if
+// `fromStart` is set.
+// Fails if the session had not been started.
+func (s *Session) Fork(name string, owner *user.User, fromStart bool) (*Session, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.game == nil {
+		return nil, s.notStarted()
+	}
+	forked := NewSessionWithClock(name, owner, s.clock)
+	forked.Budget = s.Budget
+	forked.Checkpoints = s.Checkpoints
+	forked.config = s.config
+	forked.game = s.game.Clone()
+	if fromStart {
+		forked.game.Reset()
+	}
+	forked.forkedFrom = s.Name
+	forked.forkedAt = forked.game.Generation()
+	return forked, nil
 }
 
 // Implement the Stringer interface.
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The time between generations of sessions whose speed was not configured.
+const DefaultInterval = time.Second
+
+// The speed of sessions running as fast as possible.
+const MaxSpeed = "max"
+
+// Translates `speed` to the time between generations. The speed may be given
+// as a number of generations per second (`10`, `0.5`), as an interval
+// (`250ms`, `1m`) or as MaxSpeed, for which the interval is zero.
+func ParseSpeed(speed string) (time.Duration, error) {
+	if strings.ToLower(speed) == MaxSpeed {
+		return 0, nil
+	}
+	if perSecond, err := strconv.ParseFloat(speed, 64); err == nil {
+		interval := time.Duration(float64(time.Second) / perSecond)
+		if perSecond <= 0 || interval <= 0 {
+			return 0, errors.New("the speed must be positive and finite")
+		}
+		return interval, nil
+	}
+	interval, err := time.ParseDuration(speed)
+	if err != nil {
+		return 0, errors.New("invalid speed " + speed)
+	}
+	if interval <= 0 {
+		return 0, errors.New("the interval must be positive")
+	}
+	return interval, nil
+}
+
+// Returns the speed corresponding to `interval` in human readable form.
+func FormatSpeed(interval time.Duration) string {
+	if interval == 0 {
+		return "as fast as possible"
+	}
+	return "every " + interval.String()
+}
+
+// A Budget bounds the CPU time spent on sessions running as fast as possible
+// by limiting how many of them may compute a generation at the same time.
+// A nil Budget imposes no limit.
+type Budget struct {
+	tokens chan struct{}
+}
+
+// Constructs a budget allowing `workers` generations to be computed at the
+// same time, at least one.
+func NewBudget(workers int) *Budget {
+	return &Budget{tokens: make(chan struct{}, max(workers, 1))}
+}
+
+// Blocks until a generation may be computed.
+func (b *Budget) acquire() {
+	if b != nil {
+		b.tokens <- struct{}{}
+	}
+}
+
+// Signals that a generation has been computed.
+func (b *Budget) release() {
+	if b != nil {
+		<-b.tokens
+	}
+}
@@ -0,0 +1,252 @@
+// Package storage keeps the users and sessions of the server in a data
+// directory so that they survive restarts of the server.
+//
+// The users are kept in users.json and every session in a file of its own
+// in the sessions directory, all written atomically: a file is either fully
+// replaced or left as it was. The previous version of every file is kept
+// alongside it and read instead if the latest version turns out to be
+// invalid. While a store is open the data directory holds a marker file, so
+// that a store opened after a crash knows that the sessions which were
+// running lost the generations since they were last saved.
+package storage
+
+import (
+	"LaaS/server/session"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const usersFile = "users.json"
+const sessionsFolder = "sessions"
+const sessionExtension = ".json"
+const previousExtension = ".prev"
+const openMarker = "open"
+
+// A Store reads and writes the data directory `dir`. It records whether the
+// server which last opened the directory crashed instead of closing it.
+type Store struct {
+	dir     string
+	crashed bool
+}
+
+// A User as it is stored, identified by their name and the SHA-256 hash of
+// their password.
+type User struct {
+	Name         string
+	PasswordHash [sha256.Size]byte
+}
+
+type storedUser struct {
+	Name         string
+	PasswordHash string
+}
+
+// Opens the data directory `dir`, creating it if it does not exist.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Join(dir, sessionsFolder), 0o755); err != nil {
+		return nil, err
+	}
+	marker := filepath.Join(dir, openMarker)
+	_, err := os.Stat(marker)
+	crashed := err == nil
+	if err := os.WriteFile(marker, nil, 0o644); err != nil {
+		return nil, err
+	}
+	if err := syncDirectory(dir); err != nil {
+		return nil, err
+	}
+	return &Store{dir, crashed}, nil
+}
+
+// Reports whether the data directory was not closed the last time it was
+// opened, i.e. the server crashed.
+func (s *Store) Crashed() bool {
+	return s.crashed
+}
+
+// Closes the store, recording that the server did not crash. The store must
+// not be written to afterwards.
+func (s *Store) Close() error {
+	if err := os.Remove(filepath.Join(s.dir, openMarker)); err != nil {
+		return err
+	}
+	return syncDirectory(s.dir)
+}
+
+// Flushes the entries of the directory `dir` to disk, so that files created,
+// renamed or removed in it survive power cuts.
+func syncDirectory(dir string) error {
+	directory, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer directory.Close()
+	return directory.Sync()
+}
+
+// Writes `data` to the file at `path` by writing a temporary file in the same
+// directory and renaming it over `path`, so that readers and crashes never
+// see a partially written file. The file being replaced is kept as the
+// previous version of `path`.
+func writeAtomically(path string, data []byte) error {
+	temporary, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temporary.Name())
+	if _, err := temporary.Write(data); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Sync(); err != nil {
+		temporary.Close()
+		return err
+	}
+	if err := temporary.Close(); err != nil {
+		return err
+	}
+	err = os.Rename(path, path+previousExtension)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.Rename(temporary.Name(), path); err != nil {
+		return err
+	}
+	return syncDirectory(filepath.Dir(path))
+}
+
+// Decodes the latest valid version of the file at `path` with `decode`,
+// falling back to its previous version if the latest one is missing or
+// invalid. Nothing is decoded if neither version exists.
+// Fails if neither version can be read and decoded.
+func readLatest(path string, decode func(data []byte) error) error {
+	var latestErr error
+	for _, candidate := range []string{path, path + previousExtension} {
+		data, err := os.ReadFile(candidate)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err == nil {
+			if err = decode(data); err == nil {
+				return nil
+			}
+		}
+		if latestErr == nil {
+			latestErr = err
+		}
+	}
+	return latestErr
+}
+
+// Replaces the stored users with `users`.
+func (s *Store) SaveUsers(users []User) error {
+	stored := make([]storedUser, len(users))
+	for idx, user := range users {
+		stored[idx] = storedUser{user.Name, hex.EncodeToString(user.PasswordHash[:])}
+	}
+	data, err := json.MarshalIndent(stored, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeAtomically(filepath.Join(s.dir, usersFile), data)
+}
+
+// Returns the stored users, none if no users have been stored.
+// Fails if the users cannot be read.
+func (s *Store) LoadUsers() ([]User, error) {
+	var users []User
+	err := readLatest(filepath.Join(s.dir, usersFile), func(data []byte) error {
+		var stored []storedUser
+		if err := json.Unmarshal(data, &stored); err != nil {
+			return errors.New("invalid " + usersFile + ": " + err.Error())
+		}
+		users = make([]User, len(stored))
+		for idx, user := range stored {
+			hash, err := hex.DecodeString(user.PasswordHash)
+			if err != nil || len(hash) != sha256.Size {
+				return errors.New("invalid password hash of user " + user.Name)
+			}
+			users[idx].Name = user.Name
+			copy(users[idx].PasswordHash[:], hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// Returns the path of the file the session named `name` is stored in. Names
+// are escaped so that they cannot refer to other files, including a leading
+// dot so that they cannot be mistaken for temporary files.
+func (s *Store) sessionPath(name string) string {
+	escaped := url.PathEscape(name)
+	if strings.HasPrefix(escaped, ".") {
+		escaped = "%2E" + escaped[1:]
+	}
+	return filepath.Join(s.dir, sessionsFolder, escaped+sessionExtension)
+}
+
+// Stores `record`, replacing the session stored under the same name.
+func (s *Store) SaveSession(record session.Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return writeAtomically(s.sessionPath(record.Name), data)
+}
+
+// Removes the session named `name`, if it is stored.
+func (s *Store) DeleteSession(name string) error {
+	path := s.sessionPath(name)
+	for _, version := range []string{path, path + previousExtension} {
+		err := os.Remove(version)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return syncDirectory(filepath.Dir(path))
+}
+
+// Returns the stored sessions in order of creation.
+// Fails if any of the sessions cannot be read.
+func (s *Store) LoadSessions() ([]session.Record, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, sessionsFolder))
+	if err != nil {
+		return nil, err
+	}
+	var records []session.Record
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), previousExtension)
+		if entry.IsDir() || strings.HasPrefix(name, ".") ||
+			!strings.HasSuffix(name, sessionExtension) || seen[name] {
+			continue
+		}
+		seen[name] = true
+		var record session.Record
+		err := readLatest(filepath.Join(s.dir, sessionsFolder, name), func(data []byte) error {
+			record = session.Record{}
+			if err := json.Unmarshal(data, &record); err != nil {
+				return errors.New("invalid session file " + name + ": " + err.Error())
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Created.Before(records[j].Created)
+	})
+	return records, nil
+}
@@ -16,6 +16,17 @@ func NewUser(username, password string) *User {
 	return &User{Name: username, password: passwordHash}
 }
 
+// Constructs a user whose password has the SHA-256 hash `passwordHash`, e.g.
+// when restoring a stored user.
+func RestoreUser(username string, passwordHash [sha256.Size]byte) *User {
+	return &User{Name: username, password: passwordHash}
+}
+
+// Returns the SHA-256 hash of the password of the user.
+func (u *User) PasswordHash() [sha256.Size]byte {
+	return u.password
+}
+
 // Checks wether the given `password` hash matched that of the user.
 func (u *User) Authorize(password string) bool {
 	return u.password == sha256.Sum256([]byte(password))